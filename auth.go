@@ -0,0 +1,124 @@
+package httptest
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Authenticator adds credentials to an outgoing request. It is applied
+// after the request's headers and cookies have been set.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthenticatorFunc adapts a function to the Authenticator interface.
+type AuthenticatorFunc func(req *http.Request) error
+
+func (f AuthenticatorFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+// MultiAuth applies each of its Authenticators in order, e.g. a bearer
+// token followed by an HMAC signature.
+type MultiAuth []Authenticator
+
+func (m MultiAuth) Authenticate(req *http.Request) error {
+	for _, a := range m {
+		if err := a.Authenticate(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BasicAuth sends HTTP Basic credentials.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (b BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(b.Username, b.Password)
+	return nil
+}
+
+// BearerAuth sends the token in an `Authorization: Bearer` header.
+type BearerAuth string
+
+func (b BearerAuth) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(b))
+	return nil
+}
+
+// APIKey sends an API key in the named header, or in the named query
+// parameter if Query is set.
+type APIKey struct {
+	Name  string
+	Value string
+	Query bool
+}
+
+func (k APIKey) Authenticate(req *http.Request) error {
+	if !k.Query {
+		req.Header.Set(k.Name, k.Value)
+		return nil
+	}
+	q := req.URL.Query()
+	q.Set(k.Name, k.Value)
+	req.URL.RawQuery = q.Encode()
+	if req.RequestURI != "" {
+		req.RequestURI = req.URL.RequestURI()
+	}
+	return nil
+}
+
+// Authenticate signs req with an HMAC of its body.
+func (c HmaxConfig) Authenticate(req *http.Request) error {
+	return c.hmax().SignRequest(req)
+}
+
+// Authenticate signs req using SignRequest.
+func (c *Canonical) Authenticate(req *http.Request) error {
+	return c.SignRequest(req)
+}
+
+// Authenticate signs req using SignRequest.
+func (s SigV4) Authenticate(req *http.Request) error {
+	return s.SignRequest(req)
+}
+
+// credentials are the authentication options of an HTML, JSON or XML
+// request.
+type credentials struct {
+	authenticator      Authenticator
+	username, password string
+	digest             bool
+}
+
+// authenticate adds credentials to req. The first of these that is set
+// is used: the request's Authenticator, its Basic auth credentials, the
+// Handler's Authenticator, the Handler's BearerToken. The Handler's
+// signing options are then applied on top. The first error stops it.
+// Digest can't be combined with SigV4, which replaces the Authorization
+// header.
+func (w *Handler) authenticate(req *http.Request, cr credentials) error {
+	if cr.digest && w.SigV4 != nil {
+		return fmt.Errorf("digest auth can't be used with SigV4, which replaces the Authorization header")
+	}
+	a := cr.authenticator
+	if a == nil && !cr.digest && (cr.username != "" || cr.password != "") {
+		a = BasicAuth{Username: cr.username, Password: cr.password}
+	}
+	if a == nil {
+		a = w.Authenticator
+	}
+	if a == nil && w.BearerToken != "" {
+		a = BearerAuth(w.BearerToken)
+	}
+	if a != nil {
+		if err := a.Authenticate(req); err != nil {
+			return err
+		}
+	}
+	return w.signRequest(req)
+}
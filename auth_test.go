@@ -0,0 +1,122 @@
+package httptest
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func AuthApp() http.Handler {
+	p := &mux{}
+	p.Handle("GET", "/auth", func(res http.ResponseWriter, req *http.Request) {
+		fmt.Fprintln(res, "AUTHORIZATION:"+req.Header.Get("Authorization"))
+		fmt.Fprintln(res, "X-API-KEY:"+req.Header.Get("X-Api-Key"))
+		fmt.Fprintln(res, "QUERY:"+req.URL.Query().Get("api_key"))
+		fmt.Fprintln(res, "SIGNATURE:"+req.Header.Get("X-Signature"))
+		fmt.Fprintln(res, "TENANT:"+req.Header.Get("X-Tenant"))
+	})
+	return p
+}
+
+func Test_Authenticator_Handler(t *testing.T) {
+	r := require.New(t)
+	w := New(AuthApp())
+	w.Authenticator = BearerAuth("tok")
+
+	res := w.HTML("/auth").Get()
+	r.Contains(res.Body.String(), "AUTHORIZATION:Bearer tok\n")
+
+	jres := w.JSON("/auth").Get()
+	r.Contains(jres.Body.String(), "AUTHORIZATION:Bearer tok\n")
+
+	xres := w.XML("/auth").Get()
+	r.Contains(xres.Body.String(), "AUTHORIZATION:Bearer tok\n")
+}
+
+func Test_Authenticator_Request_Overrides_Handler(t *testing.T) {
+	r := require.New(t)
+	w := New(AuthApp())
+	w.Authenticator = BearerAuth("tok")
+
+	req := w.HTML("/auth")
+	req.Authenticator = APIKey{Name: "X-Api-Key", Value: "k3y"}
+	res := req.Get()
+	r.Contains(res.Body.String(), "AUTHORIZATION:\n")
+	r.Contains(res.Body.String(), "X-API-KEY:k3y\n")
+
+	req = w.HTML("/auth")
+	req.SetBasicAuth("mark", "pass")
+	res = req.Get()
+	r.Contains(res.Body.String(), "AUTHORIZATION:Basic ")
+}
+
+func Test_Authenticator_APIKey_Query(t *testing.T) {
+	r := require.New(t)
+	w := New(AuthApp())
+
+	req := w.JSON("/auth?a=b")
+	req.Authenticator = APIKey{Name: "api_key", Value: "k3y", Query: true}
+	res := req.Get()
+	r.Contains(res.Body.String(), "QUERY:k3y\n")
+}
+
+func Test_Authenticator_Multi_And_Func(t *testing.T) {
+	r := require.New(t)
+	w := New(AuthApp())
+
+	tenant := AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set("X-Tenant", "acme")
+		return nil
+	})
+	w.Authenticator = MultiAuth{BearerAuth("tok"), tenant, HmaxConfig{Secret: "secret"}}
+
+	res := w.HTML("/auth").Get()
+	r.Contains(res.Body.String(), "AUTHORIZATION:Bearer tok\n")
+	r.Contains(res.Body.String(), "TENANT:acme\n")
+	r.Contains(res.Body.String(), "SIGNATURE:"+HmaxConfig{Secret: "secret"}.Sign(nil)+"\n")
+}
+
+func Test_Authenticator_Error_Is_Not_Sent(t *testing.T) {
+	r := require.New(t)
+	var calls int
+	w := New(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		calls++
+	}))
+	w.Authenticator = AuthenticatorFunc(func(req *http.Request) error {
+		return fmt.Errorf("token expired")
+	})
+
+	res := w.HTML("/auth").Get()
+	r.EqualError(res.Err, "token expired")
+	r.Equal(0, res.Code)
+
+	_, err := w.HTML("/auth").Do("POST", nil)
+	r.EqualError(err, "token expired")
+
+	_, err = w.JSON("/auth").Do("POST", nil)
+	r.EqualError(err, "token expired")
+
+	xres := w.XML("/auth").Get()
+	r.EqualError(xres.Err, "token expired")
+
+	_, err = w.Client().Get("http://example.com/auth")
+	r.Error(err)
+	r.Contains(err.Error(), "token expired")
+
+	r.Equal(0, calls)
+}
+
+func Test_Signing_Error_Is_Not_Sent(t *testing.T) {
+	r := require.New(t)
+	w := New(AuthApp())
+	w.SigV4 = &SigV4{Service: "s3"}
+
+	req, err := http.NewRequest("PUT", "/auth", iotest.ErrReader(fmt.Errorf("broken body")))
+	r.NoError(err)
+	res := w.HTML("/auth").Perform(req)
+	r.EqualError(res.Err, "broken body")
+	r.Equal(0, res.Code)
+}
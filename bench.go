@@ -0,0 +1,170 @@
+package httptest
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Bench benchmarks the handler with req. The request is prepared once,
+// with the Handler's headers, cookies and authentication, and replayed
+// b.N times into a reused ResponseWriter. Nonce based signatures, such as
+// Canonical's, are therefore only valid for the first iteration.
+//
+// Besides the usual numbers, Bench reports handler-ns/op,
+// handler-allocs/op and handler-B/op: the cost of the handler alone,
+// after subtracting the cost of replaying the request to a handler that
+// does nothing. A 5xx response from any iteration fails the benchmark.
+func (w *Handler) Bench(b *testing.B, req *http.Request) {
+	b.Helper()
+	sreq, err := w.serverRequest(req)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w.bench(b, sreq)
+}
+
+// Bench benchmarks the handler with the request that Do(method, body)
+// would send, or a multipart one when files are given, as Curl renders
+// it. Set a Content-Type in Headers for the handler to parse a form
+// body. See Handler.Bench.
+func (r *Request) Bench(b *testing.B, method string, body interface{}, files ...File) {
+	b.Helper()
+	req, err := r.prepared(method, body, files...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	r.handler.bench(b, req)
+}
+
+// Bench benchmarks the handler with the request that method would send
+// with body. See Handler.Bench.
+func (r *JSON) Bench(b *testing.B, method string, body interface{}) {
+	b.Helper()
+	req, err := r.prepared(method, body)
+	if err != nil {
+		b.Fatal(err)
+	}
+	r.handler.bench(b, req)
+}
+
+// Bench benchmarks the handler with the request that method would send
+// with body. See Handler.Bench.
+func (r *XML) Bench(b *testing.B, method string, body interface{}) {
+	b.Helper()
+	req, err := r.prepared(method, body)
+	if err != nil {
+		b.Fatal(err)
+	}
+	r.handler.bench(b, req)
+}
+
+func (w *Handler) bench(b *testing.B, req *http.Request) {
+	b.Helper()
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if req.RequestURI == "" {
+		req.RequestURI = req.URL.RequestURI()
+	}
+	w.applyTLS(req)
+	w.applyProto(req)
+
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	base := replay(noop, req, body, b.N)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	got := replay(w.Handler, req, body, b.N)
+	b.StopTimer()
+
+	n := float64(b.N)
+	b.ReportMetric(float64(got.elapsed-base.elapsed)/n, "handler-ns/op")
+	b.ReportMetric(float64(int64(got.allocs)-int64(base.allocs))/n, "handler-allocs/op")
+	b.ReportMetric(float64(int64(got.bytes)-int64(base.bytes))/n, "handler-B/op")
+	if got.failed != 0 {
+		b.Errorf("%s %s responded with %d", req.Method, req.URL, got.failed)
+	}
+}
+
+type replayStats struct {
+	elapsed time.Duration
+	allocs  uint64
+	bytes   uint64
+	// failed is the first 5xx status returned, if any.
+	failed int
+}
+
+// replay serves a copy of req with body to h n times, reusing the same
+// request, body reader and ResponseWriter, and measures the run. Each
+// iteration gets its own copy of the header, so a handler that changes it
+// doesn't affect the next; the copy is also made for the baseline and so
+// is subtracted from the handler's cost.
+func replay(h http.Handler, req *http.Request, body []byte, n int) replayStats {
+	r := new(http.Request)
+	rd := bytes.NewReader(body)
+	rc := ioutil.NopCloser(rd)
+	rw := &benchWriter{header: http.Header{}}
+	var failed int
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		*r = *req
+		rd.Seek(0, io.SeekStart)
+		r.Header = req.Header.Clone()
+		r.Body = rc
+		rw.reset()
+		h.ServeHTTP(rw, r)
+		if failed == 0 && rw.status >= 500 {
+			failed = rw.status
+		}
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return replayStats{
+		elapsed: elapsed,
+		allocs:  after.Mallocs - before.Mallocs,
+		bytes:   after.TotalAlloc - before.TotalAlloc,
+		failed:  failed,
+	}
+}
+
+// benchWriter is a ResponseWriter that discards the body, so it can be
+// reused without allocating.
+type benchWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *benchWriter) reset() {
+	for k := range w.header {
+		delete(w.header, k)
+	}
+	w.status = 0
+}
+
+func (w *benchWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *benchWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *benchWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return len(b), nil
+}
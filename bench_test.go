@@ -0,0 +1,105 @@
+package httptest
+
+import (
+	"flag"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var benchSink []byte
+
+func BenchApp() http.Handler {
+	p := &mux{}
+	p.Handle("GET", "/static", func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(200)
+	})
+	p.Handle("POST", "/alloc", func(res http.ResponseWriter, req *http.Request) {
+		benchSink = make([]byte, 4096)
+		req.Body.Read(benchSink)
+		res.Write(benchSink[:2])
+	})
+	p.Handle("GET", "/fail", func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(500)
+	})
+	return p
+}
+
+// benchmark runs fn with a fixed number of iterations.
+func benchmark(t *testing.T, fn func(b *testing.B)) testing.BenchmarkResult {
+	f := flag.Lookup("test.benchtime")
+	old := f.Value.String()
+	require.NoError(t, f.Value.Set("500x"))
+	defer f.Value.Set(old)
+	return testing.Benchmark(fn)
+}
+
+func Test_Bench_Subtracts_Overhead(t *testing.T) {
+	r := require.New(t)
+	w := New(BenchApp())
+	w.Headers["X-Bench"] = "1"
+	w.Cookies = "a=1"
+
+	res := benchmark(t, func(b *testing.B) {
+		req, _ := http.NewRequest("GET", "/static", nil)
+		w.Bench(b, req)
+	})
+	r.Equal(500, res.N)
+	r.True(res.Extra["handler-allocs/op"] < 0.5, res.Extra)
+
+	res = benchmark(t, func(b *testing.B) {
+		w.JSON("/alloc").Bench(b, "POST", map[string]string{"name": "mark"})
+	})
+	r.InDelta(1, res.Extra["handler-allocs/op"], 0.5, res.Extra)
+	r.InDelta(4096, res.Extra["handler-B/op"], 512, res.Extra)
+}
+
+func Test_Bench_Fails_On_5xx(t *testing.T) {
+	r := require.New(t)
+	w := New(BenchApp())
+	var failed bool
+	benchmark(t, func(b *testing.B) {
+		w.HTML("/fail").Bench(b, "GET", nil)
+		failed = b.Failed()
+	})
+	r.True(failed)
+}
+
+func Test_Bench_Each_Iteration(t *testing.T) {
+	r := require.New(t)
+	var calls, missing int
+	w := New(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		calls++
+		if req.Header.Get("X-Bench") == "" {
+			missing++
+		}
+		req.Header.Del("X-Bench")
+		if calls == 2 {
+			res.WriteHeader(503)
+		}
+	}))
+	w.Headers["X-Bench"] = "1"
+
+	var failed bool
+	benchmark(t, func(b *testing.B) {
+		calls, missing = 0, 0
+		req, _ := http.NewRequest("GET", "/", nil)
+		w.Bench(b, req)
+		failed = b.Failed()
+	})
+	r.True(failed)
+	r.Zero(missing)
+}
+
+func Benchmark_Handler_Bench(b *testing.B) {
+	w := New(App())
+	req, _ := http.NewRequest("GET", "/get", nil)
+	w.Bench(b, req)
+}
+
+func Benchmark_Request_Bench(b *testing.B) {
+	req := New(App()).HTML("/sessions/set")
+	req.Headers["Content-Type"] = "application/x-www-form-urlencoded"
+	req.Bench(b, "POST", User{Name: "mark"})
+}
@@ -0,0 +1,209 @@
+package httptest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Canonical signs and verifies requests with an HMAC over a canonical
+// form of the request: method, path, sorted query, the listed headers,
+// a timestamp, a nonce and a hash of the body. Unlike HmaxConfig alone
+// this prevents captured requests from being replayed or retargeted.
+//
+// The canonical string is the newline separated list of:
+//
+//	METHOD
+//	/escaped/path
+//	sorted=query&string=
+//	name:value for each of Headers, lower cased and sorted by name
+//	timestamp (unix seconds)
+//	nonce
+//	hex encoded SHA-256 of the body
+//
+// A Canonical must not be copied after first use.
+type Canonical struct {
+	HmaxConfig
+	// Headers are the names of the headers covered by the signature.
+	// "Host" refers to the request's host.
+	Headers []string
+	// TimestampHeader and NonceHeader default to X-Timestamp and
+	// X-Nonce.
+	TimestampHeader string
+	NonceHeader     string
+	// Skew is how far a request's timestamp may be from Now when
+	// verifying. Defaults to five minutes.
+	Skew time.Duration
+	// Now returns the current time; defaults to time.Now.
+	Now func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// SignRequest sets the timestamp, nonce and signature headers on req.
+func (c *Canonical) SignRequest(req *http.Request) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	req.Header.Set(c.timestampHeader(), strconv.FormatInt(c.now().Unix(), 10))
+	req.Header.Set(c.nonceHeader(), hex.EncodeToString(nonce))
+
+	s, err := c.canonical(req)
+	if err != nil {
+		return err
+	}
+	h := c.hmax()
+	req.Header.Set(h.Header, h.Sign([]byte(s)))
+	return nil
+}
+
+// VerifyRequest checks the signature of req, that its timestamp is
+// within Skew of Now, and that its nonce has not been seen before.
+func (c *Canonical) VerifyRequest(req *http.Request) error {
+	h := c.hmax()
+	sig := req.Header.Get(h.Header)
+	if sig == "" {
+		return fmt.Errorf("missing %s header", h.Header)
+	}
+	ts := req.Header.Get(c.timestampHeader())
+	if ts == "" {
+		return fmt.Errorf("missing %s header", c.timestampHeader())
+	}
+	nonce := req.Header.Get(c.nonceHeader())
+	if nonce == "" {
+		return fmt.Errorf("missing %s header", c.nonceHeader())
+	}
+
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", ts)
+	}
+	now := c.now()
+	d := now.Sub(time.Unix(sec, 0))
+	if d < 0 {
+		d = -d
+	}
+	if d > c.skew() {
+		return fmt.Errorf("timestamp %s is outside the allowed skew of %s", ts, c.skew())
+	}
+
+	s, err := c.canonical(req)
+	if err != nil {
+		return err
+	}
+	if _, err := h.Verify(sig, []byte(s)); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seen == nil {
+		c.seen = map[string]time.Time{}
+	}
+	for n, t := range c.seen {
+		if now.Sub(t) > 2*c.skew() {
+			delete(c.seen, n)
+		}
+	}
+	if _, ok := c.seen[nonce]; ok {
+		return fmt.Errorf("nonce %s has already been used", nonce)
+	}
+	c.seen[nonce] = now
+	return nil
+}
+
+// Middleware wraps next, rejecting requests that fail VerifyRequest
+// with RejectStatus and RejectBody.
+func (c *Canonical) Middleware(next http.Handler) http.Handler {
+	return c.reject(c.VerifyRequest, next)
+}
+
+func (c *Canonical) canonical(req *http.Request) (string, error) {
+	sum, err := bodyHash(req)
+	if err != nil {
+		return "", err
+	}
+
+	q := req.URL.Query()
+	for _, v := range q {
+		sort.Strings(v)
+	}
+
+	var hs []string
+	for _, n := range c.Headers {
+		v := req.Header.Get(n)
+		if strings.EqualFold(n, "Host") {
+			v = req.Host
+		}
+		hs = append(hs, strings.ToLower(n)+":"+strings.TrimSpace(v))
+	}
+	sort.Strings(hs)
+
+	lines := []string{
+		strings.ToUpper(req.Method),
+		req.URL.EscapedPath(),
+		q.Encode(),
+	}
+	lines = append(lines, hs...)
+	lines = append(lines,
+		req.Header.Get(c.timestampHeader()),
+		req.Header.Get(c.nonceHeader()),
+		sum,
+	)
+	return strings.Join(lines, "\n"), nil
+}
+
+// bodyHash returns the hex encoded SHA-256 of the request body, leaving
+// the body readable.
+func bodyHash(req *http.Request) (string, error) {
+	var b []byte
+	if req.Body != nil {
+		var err error
+		b, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (c *Canonical) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c *Canonical) skew() time.Duration {
+	if c.Skew == 0 {
+		return 5 * time.Minute
+	}
+	return c.Skew
+}
+
+func (c *Canonical) timestampHeader() string {
+	if c.TimestampHeader == "" {
+		return "X-Timestamp"
+	}
+	return c.TimestampHeader
+}
+
+func (c *Canonical) nonceHeader() string {
+	if c.NonceHeader == "" {
+		return "X-Nonce"
+	}
+	return c.NonceHeader
+}
@@ -0,0 +1,89 @@
+package httptest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func signedCanonical(t *testing.T, c *Canonical, method, u, body string) *http.Request {
+	req, err := http.NewRequest(method, u, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	require.NoError(t, c.SignRequest(req))
+	return req
+}
+
+func Test_Canonical_Handler(t *testing.T) {
+	r := require.New(t)
+	server := &Canonical{HmaxConfig: HmaxConfig{Secret: "secret"}, Headers: []string{"Accept"}}
+	w := New(server.Middleware(SignedApp()))
+
+	res := w.JSON("/hook").Post(User{Name: "Mark"})
+	r.Equal(401, res.Code)
+
+	w.Canonical = &Canonical{HmaxConfig: HmaxConfig{Secret: "secret"}, Headers: []string{"Accept"}}
+	res = w.JSON("/hook").Post(User{Name: "Mark"})
+	r.Equal(200, res.Code)
+	r.Contains(res.Body.String(), `BODY:{"Name":"Mark"}`)
+
+	w.Canonical.Secret = "wrong"
+	res = w.JSON("/hook").Post(User{Name: "Mark"})
+	r.Equal(401, res.Code)
+}
+
+func Test_Canonical_Replay(t *testing.T) {
+	r := require.New(t)
+	c := &Canonical{HmaxConfig: HmaxConfig{Secret: "secret"}}
+
+	req := signedCanonical(t, c, "POST", "/widgets", `{"name":"Mark"}`)
+	r.NoError(c.VerifyRequest(req))
+
+	err := c.VerifyRequest(req)
+	r.Error(err)
+	r.Contains(err.Error(), "already been used")
+}
+
+func Test_Canonical_Skew(t *testing.T) {
+	r := require.New(t)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	client := &Canonical{HmaxConfig: HmaxConfig{Secret: "secret"}, Now: func() time.Time { return now }}
+	server := &Canonical{HmaxConfig: HmaxConfig{Secret: "secret"}, Skew: time.Minute}
+
+	server.Now = func() time.Time { return now.Add(2 * time.Minute) }
+	err := server.VerifyRequest(signedCanonical(t, client, "GET", "/widgets", ""))
+	r.Error(err)
+	r.Contains(err.Error(), "skew")
+
+	server.Now = func() time.Time { return now.Add(-2 * time.Minute) }
+	r.Error(server.VerifyRequest(signedCanonical(t, client, "GET", "/widgets", "")))
+
+	server.Now = func() time.Time { return now.Add(30 * time.Second) }
+	r.NoError(server.VerifyRequest(signedCanonical(t, client, "GET", "/widgets", "")))
+}
+
+func Test_Canonical_Covers_Request(t *testing.T) {
+	r := require.New(t)
+	c := &Canonical{HmaxConfig: HmaxConfig{Secret: "secret"}, Headers: []string{"Content-Type"}}
+
+	req := signedCanonical(t, c, "GET", "/widgets?b=2&a=1&a=0", "")
+	req.URL.RawQuery = "a=0&a=1&b=2"
+	r.NoError(c.VerifyRequest(req))
+
+	table := []func(*http.Request){
+		func(req *http.Request) { req.Method = "DELETE" },
+		func(req *http.Request) { req.URL.Path = "/admin" },
+		func(req *http.Request) { req.URL.RawQuery = "a=1&b=3" },
+		func(req *http.Request) { req.Header.Set("Content-Type", "text/plain") },
+		func(req *http.Request) { req.Body = http.NoBody },
+		func(req *http.Request) { req.Header.Del("X-Nonce") },
+	}
+	for _, tamper := range table {
+		req := signedCanonical(t, c, "POST", "/widgets?a=1&b=2", `{"name":"Mark"}`)
+		tamper(req)
+		r.Error(c.VerifyRequest(req))
+	}
+}
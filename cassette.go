@@ -0,0 +1,272 @@
+package httptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secrets removed from a cassette.
+const Redacted = "[REDACTED]"
+
+// CassetteMode says whether a Cassette records or replays.
+type CassetteMode int
+
+const (
+	// Replay answers requests from the cassette file.
+	Replay CassetteMode = iota
+	// Record sends requests to the network and records them, replacing
+	// the interactions loaded from the cassette file.
+	Record
+)
+
+// RecordEnv is the environment variable that, when set, makes
+// NewCassette record instead of replay.
+const RecordEnv = "HTTPTEST_RECORD"
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Cassette is an http.RoundTripper that records real interactions to a
+// file once and replays them afterwards, so code that calls other
+// services can be tested offline.
+//
+// In Replay mode a request that matches no recorded interaction is sent
+// to Transport but not recorded. Only Record mode records. Strict, in
+// either mode, only replays: a missing cassette file or an unmatched
+// request fails. Secrets are removed before interactions are matched or
+// saved: the values of RedactHeaders, and any occurrence of RedactValues.
+type Cassette struct {
+	Path      string
+	Mode      CassetteMode
+	Strict    bool
+	Transport http.RoundTripper
+	// Match reports whether a recorded request matches a live one,
+	// both redacted. Defaults to comparing method, URL and body.
+	Match         func(live, recorded RecordedRequest) bool
+	RedactHeaders []string
+	RedactValues  []string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+	dirty        bool
+	missing      bool
+	// rerecorded is set once Record mode has dropped the loaded
+	// interactions.
+	rerecorded bool
+}
+
+// NewCassette loads the cassette at path, if it exists, and replays it.
+// It records instead when the RecordEnv environment variable is set.
+// Call Save when done.
+func NewCassette(path string) (*Cassette, error) {
+	c := &Cassette{Path: path, Mode: Replay}
+	if os.Getenv(RecordEnv) != "" {
+		c.Mode = Record
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		c.missing = true
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.interactions); err != nil {
+		return nil, fmt.Errorf("could not read cassette %s: %s", path, err)
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// Client returns an *http.Client using the cassette.
+func (c *Cassette) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// Interactions returns the interactions in the cassette.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction{}, c.interactions...)
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	live := c.redactRequest(RecordedRequest{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Headers: req.Header.Clone(),
+		Body:    string(body),
+	})
+
+	if c.Strict && c.missing {
+		return nil, fmt.Errorf("cassette %s does not exist", c.Path)
+	}
+	if c.Mode == Replay || c.Strict {
+		if i, ok := c.find(live); ok {
+			return c.interactions[i].Response.toHTTP(req), nil
+		}
+		if c.Strict {
+			return nil, fmt.Errorf("cassette %s has no interaction for %s %s", c.Path, live.Method, live.URL)
+		}
+	}
+
+	t := c.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	res, err := t.RoundTrip(req)
+	if err != nil || c.Mode != Record {
+		return res, err
+	}
+	rb, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(rb))
+
+	rec := RecordedResponse{
+		Status:  res.StatusCode,
+		Headers: c.redactHeaders(res.Header.Clone()),
+		Body:    c.redactString(string(rb)),
+	}
+	c.mu.Lock()
+	if !c.rerecorded {
+		c.interactions, c.used = nil, nil
+		c.rerecorded = true
+	}
+	c.interactions = append(c.interactions, Interaction{Request: live, Response: rec})
+	c.used = append(c.used, true)
+	c.dirty = true
+	c.mu.Unlock()
+	return res, nil
+}
+
+// Save writes the cassette to Path if anything was recorded.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	b, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(c.Path, b, 0644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// find returns the first unused interaction matching live, or else the
+// last used one, so repeated requests replay recorded sequences in order.
+func (c *Cassette) find(live RecordedRequest) (int, bool) {
+	match := c.Match
+	if match == nil {
+		match = func(live, rec RecordedRequest) bool {
+			return live.Method == rec.Method && live.URL == rec.URL && live.Body == rec.Body
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	last := -1
+	for i, in := range c.interactions {
+		if !match(live, in.Request) {
+			continue
+		}
+		if !c.used[i] {
+			c.used[i] = true
+			return i, true
+		}
+		last = i
+	}
+	return last, last >= 0
+}
+
+func (c *Cassette) redactRequest(r RecordedRequest) RecordedRequest {
+	r.URL = c.redactString(r.URL)
+	r.Headers = c.redactHeaders(r.Headers)
+	r.Body = c.redactString(r.Body)
+	return r
+}
+
+func (c *Cassette) redactHeaders(h http.Header) http.Header {
+	for _, k := range c.RedactHeaders {
+		if _, ok := h[http.CanonicalHeaderKey(k)]; ok {
+			h.Set(k, Redacted)
+		}
+	}
+	for k, vs := range h {
+		for i, v := range vs {
+			vs[i] = c.redactString(v)
+		}
+		h[k] = vs
+	}
+	return h
+}
+
+func (c *Cassette) redactString(s string) string {
+	for _, v := range c.RedactValues {
+		if v != "" {
+			s = strings.Replace(s, v, Redacted, -1)
+		}
+	}
+	return s
+}
+
+func (r RecordedResponse) toHTTP(req *http.Request) *http.Response {
+	h := r.Headers.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          ioutil.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
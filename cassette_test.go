@@ -0,0 +1,200 @@
+package httptest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// WeatherApp calls the weather service at base using client.
+func WeatherApp(client *http.Client, base string) http.Handler {
+	p := &mux{}
+	p.Handle("GET", "/forecast", func(res http.ResponseWriter, req *http.Request) {
+		wreq, _ := http.NewRequest("GET", base+"/today?key=sekrit", nil)
+		wreq.Header.Set("Authorization", "Bearer sekrit")
+		wres, err := client.Do(wreq)
+		if err != nil {
+			res.WriteHeader(502)
+			fmt.Fprint(res, err)
+			return
+		}
+		defer wres.Body.Close()
+		b, _ := ioutil.ReadAll(wres.Body)
+		fmt.Fprint(res, "FORECAST:"+string(b))
+	})
+	return p
+}
+
+func Test_Cassette_Record_Replay(t *testing.T) {
+	r := require.New(t)
+	path := filepath.Join(t.TempDir(), "cassettes", "weather.json")
+
+	s := NewStub()
+	s.On("GET", "/today").WithQuery("key", "sekrit").Reply(200, "sunny").Times(1)
+
+	c, err := NewCassette(path)
+	r.NoError(err)
+	r.Equal(Replay, c.Mode)
+	c.Mode = Record
+	c.RedactHeaders = []string{"Authorization"}
+	c.RedactValues = []string{"sekrit"}
+
+	w := New(WeatherApp(c.Client(), s.URL))
+	res := w.HTML("/forecast").Get()
+	r.Equal("FORECAST:sunny", res.Body.String())
+	r.NoError(c.Save())
+	s.Close()
+	s.Verify(t)
+
+	b, err := ioutil.ReadFile(path)
+	r.NoError(err)
+	r.NotContains(string(b), "sekrit")
+	r.Contains(string(b), Redacted)
+
+	c, err = NewCassette(path)
+	r.NoError(err)
+	r.Equal(Replay, c.Mode)
+	c.Strict = true
+	c.RedactHeaders = []string{"Authorization"}
+	c.RedactValues = []string{"sekrit"}
+
+	w = New(WeatherApp(c.Client(), s.URL))
+	res = w.HTML("/forecast").Get()
+	r.Equal("FORECAST:sunny", res.Body.String())
+	res = w.HTML("/forecast").Get()
+	r.Equal("FORECAST:sunny", res.Body.String())
+}
+
+func Test_Cassette_Strict(t *testing.T) {
+	r := require.New(t)
+	path := filepath.Join(t.TempDir(), "empty.json")
+	r.NoError(ioutil.WriteFile(path, []byte("[]"), 0644))
+
+	c, err := NewCassette(path)
+	r.NoError(err)
+	c.Strict = true
+
+	w := New(WeatherApp(c.Client(), "http://weather.invalid"))
+	res := w.HTML("/forecast").Get()
+	r.Equal(502, res.Code)
+	r.Contains(res.Body.String(), "has no interaction for GET http://weather.invalid/today")
+}
+
+func Test_Cassette_Strict_Missing_File(t *testing.T) {
+	r := require.New(t)
+	path := filepath.Join(t.TempDir(), "missing.json")
+
+	os.Setenv(RecordEnv, "1")
+	c, err := NewCassette(path)
+	os.Unsetenv(RecordEnv)
+	r.NoError(err)
+	r.Equal(Record, c.Mode)
+	c.Strict = true
+
+	w := New(WeatherApp(c.Client(), "http://weather.invalid"))
+	res := w.HTML("/forecast").Get()
+	r.Equal(502, res.Code)
+	r.Contains(res.Body.String(), "missing.json does not exist")
+}
+
+func Test_Cassette_Replay_Does_Not_Record(t *testing.T) {
+	r := require.New(t)
+	path := filepath.Join(t.TempDir(), "live.json")
+	s := NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(res, "live")
+	}))
+	defer s.Close()
+
+	c, err := NewCassette(path)
+	r.NoError(err)
+	res, err := c.Client().Get(s.URL)
+	r.NoError(err)
+	b, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	r.Equal("live", string(b))
+	r.Empty(c.Interactions())
+	r.NoError(c.Save())
+	_, err = os.Stat(path)
+	r.True(os.IsNotExist(err))
+}
+
+func Test_Cassette_Rerecord(t *testing.T) {
+	r := require.New(t)
+	path := filepath.Join(t.TempDir(), "rerecord.json")
+	version := "v1"
+	s := NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(res, version)
+	}))
+	defer s.Close()
+
+	get := func(c *Cassette) string {
+		res, err := c.Client().Get(s.URL + "/version")
+		r.NoError(err)
+		defer res.Body.Close()
+		b, err := ioutil.ReadAll(res.Body)
+		r.NoError(err)
+		return string(b)
+	}
+
+	for _, v := range []string{"v1", "v2"} {
+		version = v
+		c, err := NewCassette(path)
+		r.NoError(err)
+		c.Mode = Record
+		r.Equal(v, get(c))
+		r.NoError(c.Save())
+	}
+
+	c, err := NewCassette(path)
+	r.NoError(err)
+	c.Strict = true
+	r.Len(c.Interactions(), 1)
+	r.Equal("v2", get(c))
+}
+
+func Test_Cassette_Sequence_And_Match(t *testing.T) {
+	r := require.New(t)
+	path := filepath.Join(t.TempDir(), "seq.json")
+
+	calls := 0
+	s := NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		calls++
+		fmt.Fprintf(res, "call %d", calls)
+	}))
+	defer s.Close()
+
+	c, err := NewCassette(path)
+	r.NoError(err)
+	c.Mode = Record
+	client := c.Client()
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(s.URL + "/n?t=" + fmt.Sprint(i))
+		r.NoError(err)
+		res.Body.Close()
+	}
+	r.NoError(c.Save())
+
+	c, err = NewCassette(path)
+	r.NoError(err)
+	c.Strict = true
+	c.Match = func(live, rec RecordedRequest) bool {
+		return live.Method == rec.Method && strings.SplitN(live.URL, "?", 2)[0] == strings.SplitN(rec.URL, "?", 2)[0]
+	}
+	client = c.Client()
+	var got []string
+	for i := 0; i < 3; i++ {
+		res, err := client.Get(s.URL + "/n?t=other")
+		r.NoError(err)
+		b, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		got = append(got, string(b))
+	}
+	r.Equal([]string{"call 1", "call 2", "call 2"}, got)
+	r.Equal(2, calls)
+}
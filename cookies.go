@@ -0,0 +1,78 @@
+package httptest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cookieHeader returns the Cookie header to send with a request.
+func (w *Handler) cookieHeader() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.Cookies
+}
+
+// addCookies adds the cookies in the Cookie header c to req, except
+// those req already has by name.
+func addCookies(req *http.Request, c string) {
+	have := map[string]bool{}
+	for _, ck := range req.Cookies() {
+		have[ck.Name] = true
+	}
+	var add []string
+	for _, part := range strings.Split(c, ";") {
+		part = strings.TrimSpace(part)
+		name := strings.SplitN(part, "=", 2)[0]
+		if part != "" && !have[name] {
+			add = append(add, part)
+		}
+	}
+	if len(add) == 0 {
+		return
+	}
+	if old := req.Header.Get("Cookie"); old != "" {
+		add = append([]string{old}, add...)
+	}
+	req.Header.Set("Cookie", strings.Join(add, "; "))
+}
+
+// mergeCookies merges the cookies set by res into the Handler's Cookies.
+// See Handler.Cookies for the policy.
+func (w *Handler) mergeCookies(res *Response) {
+	// Read the live headers, as the Handler always has, rather than the
+	// snapshot taken by Result when the body is first written.
+	set := (&http.Response{Header: res.Header()}).Cookies()
+	if len(set) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	jar := (&http.Request{Header: http.Header{"Cookie": {w.Cookies}}}).Cookies()
+	now := time.Now()
+	for _, c := range set {
+		expired := c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(now))
+		i := 0
+		for ; i < len(jar); i++ {
+			if jar[i].Name == c.Name {
+				break
+			}
+		}
+		switch {
+		case expired && i < len(jar):
+			jar = append(jar[:i], jar[i+1:]...)
+		case expired:
+		case i < len(jar):
+			jar[i].Value = c.Value
+		default:
+			jar = append(jar, &http.Cookie{Name: c.Name, Value: c.Value})
+		}
+	}
+
+	pairs := make([]string, len(jar))
+	for i, c := range jar {
+		pairs[i] = c.String()
+	}
+	w.Cookies = strings.Join(pairs, "; ")
+}
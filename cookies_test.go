@@ -0,0 +1,84 @@
+package httptest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func CookieApp() http.Handler {
+	p := &mux{}
+	p.Handle("GET", "/set", func(res http.ResponseWriter, req *http.Request) {
+		for k, v := range req.URL.Query() {
+			http.SetCookie(res, &http.Cookie{Name: k, Value: v[0], Path: "/"})
+		}
+	})
+	p.Handle("GET", "/delete", func(res http.ResponseWriter, req *http.Request) {
+		http.SetCookie(res, &http.Cookie{Name: req.URL.Query().Get("name"), MaxAge: -1})
+	})
+	p.Handle("GET", "/get", func(res http.ResponseWriter, req *http.Request) {
+		var names []string
+		for _, c := range req.Cookies() {
+			names = append(names, c.Name+"="+c.Value)
+		}
+		fmt.Fprint(res, strings.Join(names, ","))
+	})
+	return p
+}
+
+func Test_Cookies_Merge(t *testing.T) {
+	r := require.New(t)
+	w := New(CookieApp())
+	w.Cookies = "given=1"
+
+	w.HTML("/set?a=1").Get()
+	w.JSON("/set?b=2").Get()
+	r.Equal("given=1; a=1; b=2", w.Cookies)
+
+	w.XML("/set?a=3").Get()
+	r.Equal("given=1; a=3; b=2", w.Cookies)
+
+	w.HTML("/get").Get()
+	r.Equal("given=1; a=3; b=2", w.Cookies)
+
+	w.HTML("/delete?name=given").Get()
+	r.Equal("a=3,b=2", w.HTML("/get").Get().Body.String())
+	r.Equal("a=3; b=2", w.Cookies)
+}
+
+func Test_Handler_Parallel(t *testing.T) {
+	w := New(App())
+	w.HmaxSecret = "secret"
+	w.CSRF = true
+	w.CSRFToken = "tok"
+	w.Headers["X-Suite"] = "parallel"
+	shared := w.HTML("/sessions/set")
+
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 8; i++ {
+			i := i
+			t.Run(fmt.Sprint(i), func(t *testing.T) {
+				t.Parallel()
+				r := require.New(t)
+				for j := 0; j < 10; j++ {
+					shared.Post(User{Name: "mark"})
+					r.Contains(w.HTML("/put").Put(User{Name: "mark"}).Body.String(), "NAME:mark")
+					r.Equal(201, w.JSON("/get").Get().Code)
+					r.Equal(201, w.XML("/get").Get().Code)
+
+					res, err := w.Client().Get(fmt.Sprintf("http://example.com/get?i=%d", i))
+					r.NoError(err)
+					res.Body.Close()
+					r.Equal(201, res.StatusCode)
+				}
+			})
+		}
+	})
+
+	r := require.New(t)
+	r.Equal("NAME:mark", w.HTML("/sessions/get").Get().Body.String())
+	r.Equal(map[string]string{"Accept": "application/html", "X-Suite": "parallel"}, shared.Headers)
+}
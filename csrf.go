@@ -0,0 +1,89 @@
+package httptest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CSRFField is the form field Buffalo reads the CSRF token from.
+const CSRFField = "authenticity_token"
+
+// CSRFHeader is the header the CSRF token is sent in.
+const CSRFHeader = "X-CSRF-Token"
+
+// csrfCookies are the cookie names a CSRF token is learned from when
+// no token is found in the page itself.
+var csrfCookies = []string{"XSRF-TOKEN", "csrf_token", "_csrf"}
+
+// learnCSRF remembers the CSRF token found in the response, if any.
+// The token is looked for in a `<meta name="csrf-token">` tag, then a
+// hidden authenticity_token input, then a CSRF cookie.
+func (w *Handler) learnCSRF(res *Response) {
+	if !w.CSRF {
+		return
+	}
+	if strings.Contains(res.Header().Get("Content-Type"), "html") || bytes.HasPrefix(bytes.TrimSpace(res.Body.Bytes()), []byte("<")) {
+		if doc, err := res.parseHTML(); err == nil {
+			if m := findFirst(doc, `meta[name="csrf-token"]`); m != nil && attr(m, "content") != "" {
+				w.setCSRFToken(attr(m, "content"))
+				return
+			}
+			if in := findFirst(doc, `input[name="`+CSRFField+`"]`); in != nil && attr(in, "value") != "" {
+				w.setCSRFToken(attr(in, "value"))
+				return
+			}
+		}
+	}
+	for _, c := range res.Result().Cookies() {
+		if contains(csrfCookies, c.Name) && c.Value != "" {
+			w.setCSRFToken(c.Value)
+			return
+		}
+	}
+}
+
+// applyCSRF sends the learned CSRF token with unsafe requests. It is
+// always sent in the X-CSRF-Token header, and is also added to url
+// encoded form bodies that don't already carry one.
+func (w *Handler) applyCSRF(req *http.Request, contentType string) {
+	token := w.csrfToken()
+	if !w.CSRF || token == "" {
+		return
+	}
+	switch req.Method {
+	case "POST", "PUT", "PATCH", "DELETE":
+	default:
+		return
+	}
+	req.Header.Set(CSRFHeader, token)
+
+	if contentType != "application/x-www-form-urlencoded" || req.Body == nil {
+		return
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return
+	}
+	vals, err := url.ParseQuery(string(b))
+	if err == nil && vals.Get(CSRFField) == "" {
+		vals.Set(CSRFField, token)
+		b = []byte(vals.Encode())
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	req.ContentLength = int64(len(b))
+}
+
+func (w *Handler) csrfToken() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.CSRFToken
+}
+
+func (w *Handler) setCSRFToken(token string) {
+	w.mu.Lock()
+	w.CSRFToken = token
+	w.mu.Unlock()
+}
@@ -0,0 +1,96 @@
+package httptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func CSRFApp() http.Handler {
+	p := &mux{}
+	p.Handle("GET", "/meta", func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(res, `<html><head><meta name="csrf-token" content="meta-token"></head></html>`)
+	})
+	p.Handle("GET", "/form", func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(res, `<form><input type="hidden" name="authenticity_token" value="form-token"></form>`)
+	})
+	p.Handle("GET", "/cookie", func(res http.ResponseWriter, req *http.Request) {
+		http.SetCookie(res, &http.Cookie{Name: "XSRF-TOKEN", Value: "cookie-token"})
+		res.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(res, `{}`)
+	})
+	check := func(res http.ResponseWriter, req *http.Request) {
+		fmt.Fprintln(res, "HEADER:"+req.Header.Get("X-CSRF-Token"))
+		fmt.Fprintln(res, "FIELD:"+req.PostFormValue("authenticity_token"))
+		fmt.Fprint(res, "NAME:"+req.PostFormValue("name"))
+	}
+	p.Handle("POST", "/post", check)
+	p.Handle("GET", "/post", check)
+	p.Handle("PATCH", "/patch", func(res http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(res).Encode(map[string]string{"token": req.Header.Get("X-CSRF-Token")})
+	})
+	return p
+}
+
+func Test_CSRF_Meta(t *testing.T) {
+	r := require.New(t)
+	w := New(CSRFApp())
+	w.CSRF = true
+
+	w.HTML("/meta").Get()
+	r.Equal("meta-token", w.CSRFToken)
+
+	res := w.HTML("/post").Post(User{Name: "Mark"})
+	r.Contains(res.Body.String(), "HEADER:meta-token")
+	r.Contains(res.Body.String(), "FIELD:meta-token")
+	r.Contains(res.Body.String(), "NAME:Mark")
+}
+
+func Test_CSRF_Hidden_Input(t *testing.T) {
+	r := require.New(t)
+	w := New(CSRFApp())
+	w.CSRF = true
+
+	w.HTML("/form").Get()
+	r.Equal("form-token", w.CSRFToken)
+
+	res := w.JSON("/patch").Patch(User{Name: "Mark"})
+	m := map[string]string{}
+	res.Bind(&m)
+	r.Equal("form-token", m["token"])
+}
+
+func Test_CSRF_Cookie(t *testing.T) {
+	r := require.New(t)
+	w := New(CSRFApp())
+	w.CSRF = true
+
+	w.JSON("/cookie").Get()
+	r.Equal("cookie-token", w.CSRFToken)
+}
+
+func Test_CSRF_Safe_Methods(t *testing.T) {
+	r := require.New(t)
+	w := New(CSRFApp())
+	w.CSRF = true
+	w.CSRFToken = "tok"
+
+	res := w.HTML("/post").Get()
+	r.Contains(res.Body.String(), "HEADER:\n")
+}
+
+func Test_CSRF_Disabled(t *testing.T) {
+	r := require.New(t)
+	w := New(CSRFApp())
+
+	w.HTML("/meta").Get()
+	r.Equal("", w.CSRFToken)
+
+	res := w.HTML("/post").Post(User{Name: "Mark"})
+	r.Contains(res.Body.String(), "HEADER:\n")
+}
@@ -0,0 +1,207 @@
+package httptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Curl renders the request that produced the response as a curl command,
+// so it can be replayed against a running server. After a Digest
+// exchange it holds the Authorization sent on the retry.
+func (r *Response) Curl() string {
+	if r.req == nil {
+		return ""
+	}
+	return r.handler.curl(r.req, r.reqBody, nil)
+}
+
+// Curl renders the request that Do(method, body) would send as a curl
+// command, or MultiPartPost and MultiPartPut when files are given. Like
+// Do, it sends no form Content-Type unless Headers has one. Nothing is
+// sent to the handler, but files are read.
+func (r *Request) Curl(method string, body interface{}, files ...File) (string, error) {
+	req, err := r.prepared(method, body, files...)
+	if err != nil {
+		return "", err
+	}
+	return r.handler.curlFor(req, r.credentials())
+}
+
+// prepared builds the request that Do(method, body) would send, or a
+// multipart one when files are given, and prepares it as Perform does.
+func (r *Request) prepared(method string, body interface{}, files ...File) (*http.Request, error) {
+	if len(files) == 0 {
+		return r.handler.prepared(method, r.URL, toReader(body), r.Headers, r.Headers["Content-Type"], r.credentials())
+	}
+	req, err := newMultipart(r.URL, method, body, files...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.handler.prepare(req, r.Headers, r.Headers["Content-Type"], r.credentials()); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Curl renders the request that Do(method, body) would send as a curl
+// command. A nil body sends no body. Nothing is sent to the handler.
+func (r *JSON) Curl(method string, body interface{}) (string, error) {
+	req, err := r.prepared(method, body)
+	if err != nil {
+		return "", err
+	}
+	return r.handler.curlFor(req, r.credentials())
+}
+
+// prepared builds the request that method would send with body, with no
+// body when it is nil, and prepares it for sending.
+func (r *JSON) prepared(method string, body interface{}) (*http.Request, error) {
+	b, err := marshal(json.Marshal, body)
+	if err != nil {
+		return nil, err
+	}
+	return r.handler.prepared(method, r.URL, bytes.NewReader(b), r.Headers, "", r.credentials())
+}
+
+// Curl renders the request that method would send with body as a curl
+// command. A nil body sends no body. Nothing is sent to the handler.
+func (r *XML) Curl(method string, body interface{}) (string, error) {
+	req, err := r.prepared(method, body)
+	if err != nil {
+		return "", err
+	}
+	return r.handler.curlFor(req, r.credentials())
+}
+
+// prepared builds the request that method would send with body, with no
+// body when it is nil, and prepares it for sending.
+func (r *XML) prepared(method string, body interface{}) (*http.Request, error) {
+	b, err := marshal(xml.Marshal, body)
+	if err != nil {
+		return nil, err
+	}
+	return r.handler.prepared(method, r.URL, bytes.NewReader(b), r.Headers, "", r.credentials())
+}
+
+// marshal encodes body with m, or returns no bytes when body is nil.
+func marshal(m func(interface{}) ([]byte, error), body interface{}) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	return m(body)
+}
+
+// curlFor reads the body of a prepared req and renders it. Digest
+// requests are rendered with curl's own --digest support.
+func (w *Handler) curlFor(req *http.Request, cr credentials) (string, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return "", err
+		}
+	}
+	var userinfo []string
+	if cr.digest {
+		userinfo = []string{cr.username, cr.password}
+	}
+	return w.curl(req, body, userinfo), nil
+}
+
+// curl renders req and its body as a curl command line. Basic auth is
+// written with -u, and so are digest credentials when userinfo is set.
+// Binary bodies are piped in with printf.
+func (w *Handler) curl(req *http.Request, body []byte, userinfo []string) string {
+	args := []string{"curl"}
+	switch {
+	case req.Method == "HEAD":
+		args = append(args, "--head")
+	case req.Method != "GET" && req.Method != "":
+		args = append(args, "-X", req.Method)
+	}
+	args = append(args, shellQuote(w.absoluteURL(req).String()))
+
+	skip := map[string]bool{"Cookie": true}
+	if userinfo != nil {
+		args = append(args, "--digest", "-u", shellQuote(userinfo[0]+":"+userinfo[1]))
+	} else if u, p, ok := req.BasicAuth(); ok {
+		args = append(args, "-u", shellQuote(u+":"+p))
+		skip["Authorization"] = true
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		args = append(args, "-H", shellQuote("Host: "+req.Host))
+	}
+
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if skip[k] {
+			continue
+		}
+		for _, v := range req.Header[k] {
+			args = append(args, "-H", shellQuote(k+": "+v))
+		}
+	}
+	if c := req.Header.Get("Cookie"); c != "" {
+		args = append(args, "-b", shellQuote(c))
+	}
+	switch {
+	case len(body) == 0:
+	case binary(body):
+		args = append([]string{"printf", "%b", printfQuote(body), "|"}, args...)
+		args = append(args, "--data-binary", "@-")
+	default:
+		args = append(args, "--data-binary", shellQuote(string(body)))
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote single quotes s for a shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// binary reports whether b can't be passed as a shell argument as is.
+func binary(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 && c != '\n' && c != '\t' || c == 0x7f {
+			return true
+		}
+	}
+	return !utf8.Valid(b)
+}
+
+// printfQuote quotes b as an argument to printf %b that prints b, so
+// bodies with NUL bytes, such as multipart files, can be piped into curl.
+func printfQuote(b []byte) string {
+	var sb strings.Builder
+	sb.WriteString("'")
+	for _, c := range b {
+		switch {
+		case c == '\\':
+			sb.WriteString(`\\`)
+		case c == '\'':
+			sb.WriteString(`'\''`)
+		case c == '\n':
+			sb.WriteString(`\n`)
+		case c == '\r':
+			sb.WriteString(`\r`)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&sb, `\0%03o`, c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteString("'")
+	return sb.String()
+}
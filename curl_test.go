@@ -0,0 +1,109 @@
+package httptest
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runCurl runs a rendered command with bash and returns its output.
+func runCurl(t *testing.T, cmd string) string {
+	if _, err := exec.LookPath("curl"); err != nil {
+		t.Skip("curl is not installed")
+	}
+	out, err := exec.Command("bash", "-c", cmd+" -s").CombinedOutput()
+	require.NoError(t, err, string(out))
+	return string(out)
+}
+
+func Test_ShellQuote(t *testing.T) {
+	r := require.New(t)
+	r.Equal(`'plain'`, shellQuote("plain"))
+	r.Equal(`'it'\''s'`, shellQuote("it's"))
+	r.Equal(`'a\\b%\n'\''\r\0000\0377'`, printfQuote([]byte("a\\b%\n'\r\x00\xff")))
+}
+
+func Test_JSON_Curl(t *testing.T) {
+	r := require.New(t)
+	w := New(JSONApp())
+	w.Headers["X-Trace"] = "abc"
+	w.Cookies = "session=123"
+	w.HmaxSecret = "secret"
+
+	req := w.JSON("/post")
+	req.Username = "mark"
+	req.Password = "pa'ss"
+	body := map[string]string{"name": "O'Brien"}
+	cmd, err := req.Curl("POST", body)
+	r.NoError(err)
+	r.True(strings.HasPrefix(cmd, `curl -X POST 'http://localhost/post' -u 'mark:pa'\''ss' -H 'Accept: application/json'`), cmd)
+	r.Regexp(`-H 'X-Signature: [^']+'`, cmd)
+	r.Contains(cmd, `-H 'X-Trace: abc' -b 'session=123' --data-binary '{"name":"O'\''Brien"}'`)
+
+	res := req.Post(body)
+	r.Equal(cmd, res.Curl())
+
+	s := NewServer(JSONApp())
+	defer s.Close()
+	w.Host = strings.TrimPrefix(s.URL, "http://")
+	cmd, err = req.Curl("POST", body)
+	r.NoError(err)
+	out := runCurl(t, cmd)
+	r.Contains(out, `"name":"O'Brien"`)
+	r.Contains(out, `"username":"mark","password":"pa'ss"`)
+}
+
+func Test_Request_Curl_Multipart(t *testing.T) {
+	r := require.New(t)
+	s := NewServer(App())
+	defer s.Close()
+	w := New(App())
+	w.Host = strings.TrimPrefix(s.URL, "http://")
+
+	file := func() File {
+		return File{ParamName: "MyFile", FileName: "foo.go", Reader: strings.NewReader("a'b\r\n\x00\xff")}
+	}
+	body := struct{ Name string }{"Foo"}
+
+	cmd, err := w.HTML("/up").Curl("POST", body, file())
+	r.NoError(err)
+	r.Contains(cmd, `-H 'Content-Type: multipart/form-data; boundary=`)
+	r.Contains(cmd, `a'\''b\r\n\0000\0377`)
+	r.Contains(cmd, "printf %b '--")
+	r.Contains(cmd, "| curl -X POST")
+	r.Equal("Foo\nfoo.go\n", runCurl(t, cmd))
+
+	res, err := w.HTML("/up").MultiPartPost(body, file())
+	r.NoError(err)
+	r.Contains(res.Curl(), `a'\''b\r\n\0000\0377`)
+}
+
+func Test_Request_Curl_Form_Digest(t *testing.T) {
+	r := require.New(t)
+	w := New(App())
+
+	cmd, err := w.HTML("/sessions/set").Curl("POST", User{Name: "mark"})
+	r.NoError(err)
+	r.Equal(`curl -X POST 'http://localhost/sessions/set' -H 'Accept: application/html' --data-binary 'name=mark'`, cmd)
+	res, err := New(App()).HTML("/sessions/set").Do("POST", User{Name: "mark"})
+	r.NoError(err)
+	r.Equal(cmd, res.Curl())
+
+	req := w.HTML("/sessions/set")
+	req.Headers["Content-Type"] = "application/x-www-form-urlencoded"
+	cmd, err = req.Curl("POST", User{Name: "mark"})
+	r.NoError(err)
+	r.Equal(`curl -X POST 'http://localhost/sessions/set' -H 'Accept: application/html' -H 'Content-Type: application/x-www-form-urlencoded' --data-binary 'name=mark'`, cmd)
+
+	cmd, err = w.HTML("/get").Curl("GET", nil)
+	r.NoError(err)
+	r.Equal(`curl 'http://localhost/get' -H 'Accept: application/html'`, cmd)
+
+	req = w.HTML("/get")
+	req.SetDigestAuth("Mufasa", "Circle of Life")
+	cmd, err = req.Curl("GET", nil)
+	r.NoError(err)
+	r.Equal(`curl 'http://localhost/get' --digest -u 'Mufasa:Circle of Life' -H 'Accept: application/html'`, cmd)
+}
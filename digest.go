@@ -0,0 +1,153 @@
+package httptest
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// SetDigestAuth sets the credentials used to answer an HTTP Digest
+// challenge. Unlike SetBasicAuth nothing is sent with the first request.
+func (r *Request) SetDigestAuth(username, password string) {
+	r.Username = username
+	r.Password = password
+	r.Digest = true
+}
+
+// SetDigestAuth sets the credentials used to answer an HTTP Digest
+// challenge. Unlike SetBasicAuth nothing is sent with the first request.
+func (r *JSON) SetDigestAuth(username, password string) {
+	r.Username = username
+	r.Password = password
+	r.Digest = true
+}
+
+// SetDigestAuth sets the credentials used to answer an HTTP Digest
+// challenge. Unlike SetBasicAuth nothing is sent with the first request.
+func (r *XML) SetDigestAuth(username, password string) {
+	r.Username = username
+	r.Password = password
+	r.Digest = true
+}
+
+// digestChallenge holds the parameters of a `WWW-Authenticate: Digest`
+// header as described in RFC 7616.
+type digestChallenge map[string]string
+
+// parseDigestChallenge returns the strongest Digest challenge among the
+// given WWW-Authenticate header values.
+func parseDigestChallenge(headers []string) (digestChallenge, bool) {
+	var found digestChallenge
+	for _, h := range headers {
+		if len(h) < 7 || !strings.EqualFold(h[:7], "Digest ") {
+			continue
+		}
+		ch := digestChallenge(parseAuthParams(h[7:]))
+		if found == nil || strings.HasPrefix(strings.ToUpper(ch["algorithm"]), "SHA-256") {
+			found = ch
+		}
+	}
+	return found, found != nil
+}
+
+// parseAuthParams parses a comma separated list of key=value pairs
+// whose values may be quoted.
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " ")
+
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			bb := &strings.Builder{}
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				bb.WriteByte(s[i])
+			}
+			val = bb.String()
+			if i < len(s) {
+				i++
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			val = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = val
+	}
+	return params
+}
+
+func (ch digestChallenge) hasher() func() hash.Hash {
+	if strings.HasPrefix(strings.ToUpper(ch["algorithm"]), "SHA-256") {
+		return sha256.New
+	}
+	return md5.New
+}
+
+// response computes the digest response for the challenge using qop=auth
+// if the server offers it.
+func (ch digestChallenge) response(method, uri, username, password, cnonce string, nc int) string {
+	h := func(s string) string {
+		hh := ch.hasher()()
+		hh.Write([]byte(s))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	ha1 := h(username + ":" + ch["realm"] + ":" + password)
+	if strings.HasSuffix(strings.ToLower(ch["algorithm"]), "-sess") {
+		ha1 = h(ha1 + ":" + ch["nonce"] + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+	if ch.qop() == "" {
+		return h(ha1 + ":" + ch["nonce"] + ":" + ha2)
+	}
+	return h(fmt.Sprintf("%s:%s:%08x:%s:%s:%s", ha1, ch["nonce"], nc, cnonce, ch.qop(), ha2))
+}
+
+func (ch digestChallenge) qop() string {
+	for _, q := range strings.Split(ch["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			return "auth"
+		}
+	}
+	return ""
+}
+
+func (ch digestChallenge) authorization(method, uri, username, password, cnonce string, nc int) string {
+	alg := ch["algorithm"]
+	if alg == "" {
+		alg = "MD5"
+	}
+	parts := []string{
+		fmt.Sprintf("username=%q", username),
+		fmt.Sprintf("realm=%q", ch["realm"]),
+		fmt.Sprintf("nonce=%q", ch["nonce"]),
+		fmt.Sprintf("uri=%q", uri),
+		"algorithm=" + alg,
+	}
+	if ch.qop() != "" {
+		parts = append(parts, "qop=auth", fmt.Sprintf("nc=%08x", nc), fmt.Sprintf("cnonce=%q", cnonce))
+	}
+	parts = append(parts, fmt.Sprintf("response=%q", ch.response(method, uri, username, password, cnonce, nc)))
+	if o, ok := ch["opaque"]; ok {
+		parts = append(parts, fmt.Sprintf("opaque=%q", o))
+	}
+	return "Digest " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,139 @@
+package httptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_Digest_RFC7616 checks the digest computation against the
+// examples in section 3.9.1 of RFC 7616.
+func Test_Digest_RFC7616(t *testing.T) {
+	r := require.New(t)
+
+	ch := digestChallenge{
+		"realm":  "http-auth@example.org",
+		"qop":    "auth, auth-int",
+		"nonce":  "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+		"opaque": "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS",
+	}
+	cnonce := "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+
+	ch["algorithm"] = "MD5"
+	r.Equal("8ca523f5e9506fed4657c9700eebdbec", ch.response("GET", "/dir/index.html", "Mufasa", "Circle of Life", cnonce, 1))
+
+	ch["algorithm"] = "SHA-256"
+	r.Equal("753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1", ch.response("GET", "/dir/index.html", "Mufasa", "Circle of Life", cnonce, 1))
+}
+
+func Test_Digest_Parse_Challenge(t *testing.T) {
+	r := require.New(t)
+
+	ch, ok := parseDigestChallenge([]string{
+		`Basic realm="x"`,
+		`Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=MD5, nonce="abc"`,
+		`Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=SHA-256, nonce="def", opaque="o\"q"`,
+	})
+	r.True(ok)
+	r.Equal("SHA-256", ch["algorithm"])
+	r.Equal("def", ch["nonce"])
+	r.Equal(`o"q`, ch["opaque"])
+	r.Equal("auth", ch.qop())
+
+	_, ok = parseDigestChallenge([]string{`Basic realm="x"`})
+	r.False(ok)
+}
+
+func DigestApp(algorithm string) http.Handler {
+	p := &mux{}
+	h := func(res http.ResponseWriter, req *http.Request) {
+		ch := digestChallenge{"realm": "devices", "qop": "auth", "nonce": "n0nce", "opaque": "op", "algorithm": algorithm}
+		auth := req.Header.Get("Authorization")
+		if len(auth) < 7 || auth[:7] != "Digest " {
+			res.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="devices", qop="auth", nonce="n0nce", opaque="op", algorithm=%s`, algorithm))
+			res.WriteHeader(401)
+			return
+		}
+		got := parseAuthParams(auth[7:])
+		want := ch.response(req.Method, got["uri"], "admin", "s3cret", got["cnonce"], 1)
+		if got["response"] != want || got["opaque"] != "op" || got["nc"] != "00000001" || got["uri"] != req.URL.RequestURI() {
+			res.WriteHeader(403)
+			return
+		}
+		jb := jBody{Method: req.Method, Username: got["username"]}
+		if req.Body != nil {
+			json.NewDecoder(req.Body).Decode(&jb)
+		}
+		json.NewEncoder(res).Encode(jb)
+	}
+	p.Handle("GET", "/device", h)
+	p.Handle("POST", "/device", h)
+	return p
+}
+
+func Test_Digest_Request(t *testing.T) {
+	r := require.New(t)
+	w := New(DigestApp("MD5"))
+
+	res := w.HTML("/device?id=1").Get()
+	r.Equal(401, res.Code)
+
+	req := w.HTML("/device?id=1")
+	req.SetDigestAuth("admin", "s3cret")
+	res = req.Get()
+	r.Equal(200, res.Code)
+	r.Contains(res.Body.String(), `"username":"admin"`)
+
+	req = w.HTML("/device")
+	req.SetDigestAuth("admin", "wrong")
+	res = req.Get()
+	r.Equal(403, res.Code)
+}
+
+func Test_Digest_JSON_Retries_Body(t *testing.T) {
+	r := require.New(t)
+	w := New(DigestApp("SHA-256"))
+
+	req := w.JSON("/device")
+	req.SetDigestAuth("admin", "s3cret")
+	res := req.Post(User{Name: "Mark"})
+	r.Equal(200, res.Code)
+
+	jb := &jBody{}
+	res.Bind(jb)
+	r.Equal("POST", jb.Method)
+	r.Equal("Mark", jb.Name)
+	r.Equal("admin", jb.Username)
+}
+
+func Test_Digest_XML(t *testing.T) {
+	r := require.New(t)
+	w := New(DigestApp("MD5"))
+
+	req := w.XML("/device")
+	req.SetDigestAuth("admin", "s3cret")
+	r.True(req.Digest)
+	res := req.Get()
+	r.Equal(200, res.Code)
+	r.Contains(res.Body.String(), `"username":"admin"`)
+}
+
+func Test_Digest_With_SigV4(t *testing.T) {
+	r := require.New(t)
+	var calls int
+	w := New(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		calls++
+	}))
+	s := sigV4Example
+	w.SigV4 = &s
+
+	req := w.HTML("/device")
+	req.SetDigestAuth("admin", "s3cret")
+	res := req.Get()
+	r.Error(res.Err)
+	r.Contains(res.Err.Error(), "SigV4")
+	r.Equal(0, calls)
+}
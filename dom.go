@@ -0,0 +1,251 @@
+package httptest
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/gobuffalo/httptest/internal/takeon/golang.org/x/net/html"
+)
+
+// parseHTML parses the body of the response into a DOM tree.
+func (r *Response) parseHTML() (*html.Node, error) {
+	return html.Parse(bytes.NewReader(r.Body.Bytes()))
+}
+
+// findAll returns every element below n that matches the selector.
+// Supported selectors are a small subset of CSS: tag names, #id,
+// .class, [attr] and [attr=value] compounds, combined with spaces
+// for descendants. For example: `form#login`, `div.actions a`,
+// `input[name="authenticity_token"]`.
+func findAll(n *html.Node, selector string) []*html.Node {
+	var parts []simpleSelector
+	for _, s := range splitSelector(selector) {
+		parts = append(parts, parseSimpleSelector(s))
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+
+	var found []*html.Node
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode && matchesChain(c, parts) {
+			found = append(found, c)
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return found
+}
+
+// splitSelector splits selector at the spaces between simple selectors,
+// keeping spaces inside [...] and quotes.
+func splitSelector(selector string) []string {
+	var parts []string
+	var quote byte
+	depth, start := 0, -1
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case depth > 0 && (c == '"' || c == '\''):
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case depth == 0 && (c == ' ' || c == '\t' || c == '\n'):
+			if start >= 0 {
+				parts = append(parts, selector[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		parts = append(parts, selector[start:])
+	}
+	return parts
+}
+
+// closingBracket returns the index of the ] that closes the [ at the
+// start of s, skipping quoted values, or len(s) if there is none.
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return len(s)
+}
+
+// findFirst returns the first element below n that matches the selector.
+func findFirst(n *html.Node, selector string) *html.Node {
+	found := findAll(n, selector)
+	if len(found) == 0 {
+		return nil
+	}
+	return found[0]
+}
+
+// matchesChain reports whether n matches the last selector in parts and
+// has ancestors matching the preceding ones, in order.
+func matchesChain(n *html.Node, parts []simpleSelector) bool {
+	last := len(parts) - 1
+	if !parts[last].matches(n) {
+		return false
+	}
+	i := last - 1
+	for p := n.Parent; p != nil && i >= 0; p = p.Parent {
+		if p.Type == html.ElementNode && parts[i].matches(p) {
+			i--
+		}
+	}
+	return i < 0
+}
+
+type attrSelector struct {
+	key   string
+	value string
+	any   bool
+}
+
+type simpleSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrSelector
+}
+
+func (s simpleSelector) matches(n *html.Node) bool {
+	if s.tag != "" && s.tag != "*" && !strings.EqualFold(s.tag, n.Data) {
+		return false
+	}
+	if s.id != "" && attr(n, "id") != s.id {
+		return false
+	}
+	if len(s.classes) > 0 {
+		have := strings.Fields(attr(n, "class"))
+		for _, c := range s.classes {
+			if !contains(have, c) {
+				return false
+			}
+		}
+	}
+	for _, a := range s.attrs {
+		v, ok := lookupAttr(n, a.key)
+		if !ok {
+			return false
+		}
+		if !a.any && v != a.value {
+			return false
+		}
+	}
+	return true
+}
+
+func parseSimpleSelector(s string) simpleSelector {
+	sel := simpleSelector{}
+	i := strings.IndexAny(s, "#.[")
+	if i < 0 {
+		sel.tag = s
+		return sel
+	}
+	sel.tag = s[:i]
+	s = s[i:]
+	for len(s) > 0 {
+		switch s[0] {
+		case '[':
+			end := closingBracket(s)
+			body := s[1:end]
+			if end < len(s) {
+				end++
+			}
+			s = s[end:]
+			if eq := strings.IndexByte(body, '='); eq >= 0 {
+				sel.attrs = append(sel.attrs, attrSelector{
+					key:   strings.TrimSpace(body[:eq]),
+					value: strings.Trim(strings.TrimSpace(body[eq+1:]), `"'`),
+				})
+				continue
+			}
+			sel.attrs = append(sel.attrs, attrSelector{key: strings.TrimSpace(body), any: true})
+		default:
+			kind := s[0]
+			s = s[1:]
+			end := strings.IndexAny(s, "#.[")
+			if end < 0 {
+				end = len(s)
+			}
+			name := s[:end]
+			s = s[end:]
+			if kind == '#' {
+				sel.id = name
+				continue
+			}
+			sel.classes = append(sel.classes, name)
+		}
+	}
+	return sel
+}
+
+func lookupAttr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attr(n *html.Node, key string) string {
+	v, _ := lookupAttr(n, key)
+	return v
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	_, ok := lookupAttr(n, key)
+	return ok
+}
+
+// textContent returns the text of n and all of its descendants with
+// surrounding and repeated whitespace collapsed.
+func textContent(n *html.Node) string {
+	bb := &strings.Builder{}
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.TextNode {
+			bb.WriteString(c.Data)
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(bb.String()), " ")
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
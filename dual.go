@@ -0,0 +1,22 @@
+package httptest
+
+import (
+	"net/http"
+	"testing"
+)
+
+// Dual runs fn twice as subtests: "in-process", with a Handler that calls
+// h.ServeHTTP directly, and "tls", with a Handler that sends requests to
+// h over a real TLS connection started with NewTLSServer. This catches
+// bugs that only show up on the wire, such as chunked encoding, header
+// size limits or Secure cookies.
+func Dual(t *testing.T, h http.Handler, fn func(t *testing.T, w *Handler)) {
+	t.Run("in-process", func(t *testing.T) {
+		fn(t, New(h))
+	})
+	t.Run("tls", func(t *testing.T) {
+		s := NewTLSServer(h)
+		t.Cleanup(s.Close)
+		fn(t, NewRemoteServer(s))
+	})
+}
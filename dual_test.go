@@ -0,0 +1,43 @@
+package httptest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Dual(t *testing.T) {
+	p := &mux{}
+	p.Handle("POST", "/login", func(res http.ResponseWriter, req *http.Request) {
+		http.SetCookie(res, &http.Cookie{Name: "user", Value: req.FormValue("name"), Secure: true})
+	})
+	p.Handle("GET", "/whoami", func(res http.ResponseWriter, req *http.Request) {
+		c, err := req.Cookie("user")
+		if err != nil {
+			res.WriteHeader(401)
+			return
+		}
+		fmt.Fprintf(res, "%s TLS:%t", c.Value, req.TLS != nil)
+	})
+
+	var runs []string
+	Dual(t, p, func(t *testing.T, w *Handler) {
+		r := require.New(t)
+		runs = append(runs, t.Name())
+
+		r.Equal(401, w.HTML("/whoami").Get().Code)
+		w.HTML("/login").Post(User{Name: "mark"})
+		res := w.HTML("/whoami").Get()
+		r.Equal(200, res.Code)
+
+		_, remote := w.Handler.(*Remote)
+		r.Equal(fmt.Sprintf("mark TLS:%t", remote), res.Body.String())
+		if remote {
+			r.True(strings.HasPrefix(res.Curl(), "curl 'https://127.0.0.1:"))
+		}
+	})
+	require.Equal(t, []string{"Test_Dual/in-process", "Test_Dual/tls"}, runs)
+}
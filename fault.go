@@ -0,0 +1,288 @@
+package httptest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Fault describes what goes wrong with a request. Any combination of
+// fields may be set.
+type Fault struct {
+	// Latency is added before the request is handled.
+	Latency time.Duration
+	// Reset drops the connection, as if it was reset by the peer.
+	Reset bool
+	// Status replaces the response with an error response, e.g. 503.
+	Status int
+	// Truncate cuts the response body to this many bytes.
+	Truncate int
+	// EarlyEOF ends the body after Truncate bytes while still claiming
+	// the full Content-Length, so readers see io.ErrUnexpectedEOF.
+	EarlyEOF bool
+	// Drip sends the body DripSize bytes at a time (default 1), waiting
+	// Drip between each.
+	Drip     time.Duration
+	DripSize int
+}
+
+// FaultRule applies a Fault to a call when it triggers: on the Nth call
+// (counting from 1), on every Every calls, or at random with the given
+// Probability. Once triggered it also applies to the next Burst-1 calls.
+type FaultRule struct {
+	Fault       Fault
+	Nth         int
+	Every       int
+	Probability float64
+	Burst       int
+}
+
+// Faults injects faults into outbound calls, with Transport, or into a
+// handler, with Handler. Random rules draw from a source seeded with
+// Seed, so a run is reproducible. The first rule that triggers wins.
+type Faults struct {
+	Seed  int64
+	Rules []FaultRule
+
+	mu     sync.Mutex
+	rnd    *rand.Rand
+	calls  int
+	bursts []int
+}
+
+// next counts a call and returns the fault to apply to it, if any.
+func (f *Faults) next() (Fault, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rnd == nil {
+		f.rnd = rand.New(rand.NewSource(f.Seed))
+		f.bursts = make([]int, len(f.Rules))
+	}
+	f.calls++
+
+	var found *Fault
+	for i, r := range f.Rules {
+		hit := f.bursts[i] > 0
+		if hit {
+			f.bursts[i]--
+		}
+		if !hit {
+			switch {
+			case r.Nth > 0 && f.calls == r.Nth:
+				hit = true
+			case r.Every > 0 && f.calls%r.Every == 0:
+				hit = true
+			case r.Probability > 0 && f.rnd.Float64() < r.Probability:
+				hit = true
+			}
+			if hit && r.Burst > 1 {
+				f.bursts[i] = r.Burst - 1
+			}
+		}
+		if hit && found == nil {
+			fault := r.Fault
+			found = &fault
+		}
+	}
+	if found == nil {
+		return Fault{}, false
+	}
+	return *found, true
+}
+
+// Calls returns the number of calls seen so far.
+func (f *Faults) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// Transport wraps next, or http.DefaultTransport if nil, so that faults
+// are injected into outbound calls.
+func (f *Faults) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return faultTransport{faults: f, next: next}
+}
+
+type faultTransport struct {
+	faults *Faults
+	next   http.RoundTripper
+}
+
+func (t faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fault, ok := t.faults.next()
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	if fault.Latency > 0 {
+		select {
+		case <-time.After(fault.Latency):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	if fault.Reset {
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	}
+	if fault.Status > 0 {
+		body := fmt.Sprintf("injected fault: %d %s", fault.Status, http.StatusText(fault.Status))
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", fault.Status, http.StatusText(fault.Status)),
+			StatusCode:    fault.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"text/plain"}},
+			Body:          ioutil.NopCloser(bytes.NewBufferString(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if fault.Truncate > 0 || fault.EarlyEOF || fault.Drip > 0 {
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		res.Body = ioutil.NopCloser(fault.body(b))
+		if !fault.EarlyEOF {
+			res.ContentLength = int64(len(fault.cut(b)))
+		}
+	}
+	return res, nil
+}
+
+// cut returns the part of b that is sent.
+func (f Fault) cut(b []byte) []byte {
+	if f.Truncate > 0 && f.Truncate < len(b) {
+		return b[:f.Truncate]
+	}
+	if f.EarlyEOF && f.Truncate == 0 {
+		return b[:len(b)/2]
+	}
+	return b
+}
+
+// body returns a reader for b with the fault's body faults applied.
+func (f Fault) body(b []byte) io.Reader {
+	var r io.Reader = bytes.NewReader(f.cut(b))
+	if f.Drip > 0 {
+		r = &dripReader{r: r, size: f.DripSize, every: f.Drip}
+	}
+	if f.EarlyEOF {
+		r = io.MultiReader(r, errReader{io.ErrUnexpectedEOF})
+	}
+	return r
+}
+
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
+
+type dripReader struct {
+	r     io.Reader
+	size  int
+	every time.Duration
+}
+
+func (d *dripReader) Read(p []byte) (int, error) {
+	size := d.size
+	if size <= 0 {
+		size = 1
+	}
+	if len(p) > size {
+		p = p[:size]
+	}
+	time.Sleep(d.every)
+	return d.r.Read(p)
+}
+
+// Handler wraps next so that faults are injected into its responses.
+// Reset hijacks and drops the connection; when the response can't be
+// hijacked, as in-process, a 502 is written instead.
+func (f *Faults) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fault, ok := f.next()
+		if !ok {
+			next.ServeHTTP(res, req)
+			return
+		}
+		if fault.Latency > 0 {
+			time.Sleep(fault.Latency)
+		}
+		if fault.Reset {
+			if hj, ok := res.(http.Hijacker); ok {
+				if conn, _, err := hj.Hijack(); err == nil {
+					if tc, ok := conn.(*net.TCPConn); ok {
+						tc.SetLinger(0)
+					}
+					conn.Close()
+					return
+				}
+			}
+			http.Error(res, "injected fault: connection reset", http.StatusBadGateway)
+			return
+		}
+		if fault.Status > 0 {
+			http.Error(res, fmt.Sprintf("injected fault: %d %s", fault.Status, http.StatusText(fault.Status)), fault.Status)
+			return
+		}
+		if fault.Truncate == 0 && !fault.EarlyEOF && fault.Drip == 0 {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, req)
+		for k, v := range rec.Header() {
+			res.Header()[k] = v
+		}
+		b := rec.Body.Bytes()
+		sent := fault.cut(b)
+		if fault.EarlyEOF {
+			res.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		} else {
+			res.Header().Set("Content-Length", strconv.Itoa(len(sent)))
+		}
+		res.WriteHeader(rec.Code)
+
+		size := len(sent)
+		if fault.Drip > 0 {
+			size = fault.DripSize
+			if size <= 0 {
+				size = 1
+			}
+		}
+		fl, _ := res.(http.Flusher)
+		for len(sent) > 0 {
+			n := size
+			if n > len(sent) {
+				n = len(sent)
+			}
+			if fault.Drip > 0 {
+				time.Sleep(fault.Drip)
+			}
+			res.Write(sent[:n])
+			if fl != nil {
+				fl.Flush()
+			}
+			sent = sent[n:]
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package httptest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func echoServer() *Server {
+	return NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(res, "0123456789")
+	}))
+}
+
+func Test_Faults_Transport_Nth_And_Burst(t *testing.T) {
+	r := require.New(t)
+	s := echoServer()
+	defer s.Close()
+
+	f := &Faults{Rules: []FaultRule{
+		{Nth: 2, Burst: 3, Fault: Fault{Status: 503}},
+	}}
+	c := &http.Client{Transport: f.Transport(nil)}
+
+	var codes []int
+	for i := 0; i < 6; i++ {
+		res, err := c.Get(s.URL)
+		r.NoError(err)
+		res.Body.Close()
+		codes = append(codes, res.StatusCode)
+	}
+	r.Equal([]int{200, 503, 503, 503, 200, 200}, codes)
+	r.Equal(6, f.Calls())
+}
+
+func Test_Faults_Transport_Reset(t *testing.T) {
+	r := require.New(t)
+	s := echoServer()
+	defer s.Close()
+
+	f := &Faults{Rules: []FaultRule{{Every: 1, Fault: Fault{Reset: true}}}}
+	c := &http.Client{Transport: f.Transport(nil)}
+	_, err := c.Get(s.URL)
+	r.Error(err)
+	r.True(errors.Is(err, syscall.ECONNRESET))
+}
+
+func Test_Faults_Transport_Body(t *testing.T) {
+	r := require.New(t)
+	s := echoServer()
+	defer s.Close()
+
+	f := &Faults{Rules: []FaultRule{
+		{Nth: 1, Fault: Fault{Truncate: 4}},
+		{Nth: 2, Fault: Fault{EarlyEOF: true, Truncate: 3}},
+		{Nth: 3, Fault: Fault{Drip: 5 * time.Millisecond, DripSize: 2, Latency: 10 * time.Millisecond}},
+	}}
+	c := &http.Client{Transport: f.Transport(nil)}
+
+	res, err := c.Get(s.URL)
+	r.NoError(err)
+	b, err := ioutil.ReadAll(res.Body)
+	r.NoError(err)
+	r.Equal("0123", string(b))
+
+	res, err = c.Get(s.URL)
+	r.NoError(err)
+	b, err = ioutil.ReadAll(res.Body)
+	r.Equal(io.ErrUnexpectedEOF, err)
+	r.Equal("012", string(b))
+
+	start := time.Now()
+	res, err = c.Get(s.URL)
+	r.NoError(err)
+	b, err = ioutil.ReadAll(res.Body)
+	r.NoError(err)
+	r.Equal("0123456789", string(b))
+	r.True(time.Since(start) >= 35*time.Millisecond)
+}
+
+func Test_Faults_Seeded_Probability(t *testing.T) {
+	r := require.New(t)
+
+	run := func(seed int64) string {
+		f := &Faults{Seed: seed, Rules: []FaultRule{{Probability: 0.5, Fault: Fault{Status: 500}}}}
+		w := New(f.Handler(App()))
+		var codes []string
+		for i := 0; i < 20; i++ {
+			codes = append(codes, fmt.Sprint(w.HTML("/get").Get().Code))
+		}
+		return strings.Join(codes, ",")
+	}
+	a := run(42)
+	r.Equal(a, run(42))
+	r.Contains(a, "500")
+	r.Contains(a, "201")
+	r.NotEqual(a, run(7))
+}
+
+func Test_Faults_Handler_Server(t *testing.T) {
+	r := require.New(t)
+	f := &Faults{Rules: []FaultRule{
+		{Nth: 1, Fault: Fault{Reset: true}},
+		{Nth: 2, Fault: Fault{EarlyEOF: true}},
+		{Nth: 3, Fault: Fault{Truncate: 4}},
+	}}
+	s := NewServer(f.Handler(App()))
+	defer s.Close()
+
+	_, err := http.Get(s.URL + "/get")
+	r.Error(err)
+
+	res, err := http.Get(s.URL + "/get")
+	r.NoError(err)
+	_, err = ioutil.ReadAll(res.Body)
+	r.Equal(io.ErrUnexpectedEOF, err)
+	res.Body.Close()
+
+	res, err = http.Get(s.URL + "/get")
+	r.NoError(err)
+	b, err := ioutil.ReadAll(res.Body)
+	r.NoError(err)
+	res.Body.Close()
+	r.Equal(201, res.StatusCode)
+	r.Equal("METH", string(b))
+}
+
+func Test_Faults_Handler_In_Process(t *testing.T) {
+	r := require.New(t)
+	f := &Faults{Rules: []FaultRule{
+		{Nth: 1, Fault: Fault{Reset: true}},
+		{Nth: 2, Fault: Fault{Drip: time.Millisecond, DripSize: 4}},
+	}}
+	w := New(f.Handler(App()))
+
+	r.Equal(502, w.HTML("/get").Get().Code)
+	res := w.HTML("/get").Get()
+	r.Equal(201, res.Code)
+	r.Equal("METHOD:GET\nHello from Get!", res.Body.String())
+}
@@ -18,7 +18,11 @@ func (r *Request) MultiPartPost(body interface{}, files ...File) (*Response, err
 	if err != nil {
 		return nil, err
 	}
-	return r.Perform(req), nil
+	res := r.Perform(req)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	return res, nil
 }
 
 func (r *Request) MultiPartPut(body interface{}, files ...File) (*Response, error) {
@@ -26,7 +30,11 @@ func (r *Request) MultiPartPut(body interface{}, files ...File) (*Response, erro
 	if err != nil {
 		return nil, err
 	}
-	return r.Perform(req), nil
+	res := r.Perform(req)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	return res, nil
 }
 
 // this helper method was inspired by this blog post by Matt Aimonetti:
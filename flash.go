@@ -0,0 +1,35 @@
+package httptest
+
+import (
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// FlashKey is the session key Buffalo keeps pending flash messages under.
+const FlashKey = "_flash"
+
+// Flash returns the pending flash messages, grouped by key, that are
+// stored in the named session of the Handler's current cookies. Once a
+// page has rendered them the messages are consumed and Flash returns
+// an empty map.
+func (w *Handler) Flash(store sessions.Store, name string) (map[string][]string, error) {
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Cookie", w.cookieHeader())
+
+	sess, err := store.Get(req, name)
+	if err != nil {
+		return nil, err
+	}
+
+	flash := map[string][]string{}
+	if m, ok := sess.Values[FlashKey].(map[string][]string); ok {
+		for k, v := range m {
+			flash[k] = v
+		}
+	}
+	return flash, nil
+}
@@ -0,0 +1,61 @@
+package httptest
+
+import (
+	"encoding/gob"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	gob.Register(map[string][]string{})
+}
+
+func FlashApp() http.Handler {
+	p := &mux{}
+	p.Handle("POST", "/widgets", func(res http.ResponseWriter, req *http.Request) {
+		sess, _ := Store.Get(req, "flash-session")
+		sess.Values[FlashKey] = map[string][]string{
+			"success": {"Widget saved"},
+		}
+		sess.Save(req, res)
+		res.Header().Set("Location", "/widgets/1")
+		res.WriteHeader(302)
+	})
+	p.Handle("GET", "/widgets/1", func(res http.ResponseWriter, req *http.Request) {
+		sess, _ := Store.Get(req, "flash-session")
+		if m, ok := sess.Values[FlashKey].(map[string][]string); ok {
+			for _, msg := range m["success"] {
+				fmt.Fprintln(res, msg)
+			}
+		}
+		delete(sess.Values, FlashKey)
+		sess.Save(req, res)
+	})
+	return p
+}
+
+func Test_Flash(t *testing.T) {
+	r := require.New(t)
+	w := New(FlashApp())
+
+	flash, err := w.Flash(Store, "flash-session")
+	r.NoError(err)
+	r.Empty(flash)
+
+	res := w.HTML("/widgets").Post(User{Name: "Mark"})
+	r.Equal(302, res.Code)
+
+	flash, err = w.Flash(Store, "flash-session")
+	r.NoError(err)
+	r.Equal([]string{"Widget saved"}, flash["success"])
+
+	res = w.HTML(res.Location()).Get()
+	r.Contains(res.Body.String(), "Widget saved")
+
+	flash, err = w.Flash(Store, "flash-session")
+	r.NoError(err)
+	r.Empty(flash)
+}
@@ -0,0 +1,234 @@
+package httptest
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gobuffalo/httptest/internal/takeon/golang.org/x/net/html"
+)
+
+// Form is an HTML form found in a response. Values holds the fields
+// the browser would submit, hidden inputs included, and can be changed
+// before calling Submit.
+type Form struct {
+	Action  string
+	Method  string
+	Enctype string
+	Values  url.Values
+	handler *Handler
+	url     string
+}
+
+// Form finds a form in the HTML body of the response. The selector may
+// be a CSS selector such as `#new-widget` or `form.search`, the form's
+// id, or its action, e.g. "/widgets" or "widgets/1", which is resolved
+// against the URL of the page. They are tried in that order.
+func (r *Response) Form(selector string) (*Form, error) {
+	doc, err := r.parseHTML()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range findAll(doc, selector) {
+		if f.Data == "form" {
+			return r.newForm(f), nil
+		}
+	}
+	forms := findAll(doc, "form")
+	for _, f := range forms {
+		if attr(f, "id") == selector {
+			return r.newForm(f), nil
+		}
+	}
+	want := r.resolve(selector)
+	for _, f := range forms {
+		if a := attr(f, "action"); a == selector || (want != "" && r.resolve(a) == want) {
+			return r.newForm(f), nil
+		}
+	}
+	return nil, fmt.Errorf("could not find form %q", selector)
+}
+
+// resolve returns ref resolved against the URL of the response, or ""
+// if either can't be parsed.
+func (r *Response) resolve(ref string) string {
+	base, err := url.Parse(r.url)
+	if err != nil {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(u).String()
+}
+
+// Set sets the value of the named field, replacing any existing values.
+func (f *Form) Set(name, value string) *Form {
+	f.Values.Set(name, value)
+	return f
+}
+
+// Submit sends the form to its action using its method and enctype.
+// Any files given are sent as a multipart request.
+func (f *Form) Submit(files ...File) (*Response, error) {
+	u, err := f.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Method == "GET" {
+		u.RawQuery = f.Values.Encode()
+		res := f.handler.HTML("%s", u.String()).Get()
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res, nil
+	}
+
+	req := f.handler.HTML("%s", u.String())
+	if f.Enctype == "multipart/form-data" || len(files) > 0 {
+		hreq, err := newMultipart(req.URL, f.Method, f.Values, files...)
+		if err != nil {
+			return nil, err
+		}
+		res := req.Perform(hreq)
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res, nil
+	}
+	req.Headers["Content-Type"] = "application/x-www-form-urlencoded"
+	return req.Do(f.Method, f.Values)
+}
+
+// resolve returns the form's action as an absolute reference relative
+// to the URL of the page it was found on.
+func (f *Form) resolve() (*url.URL, error) {
+	base, err := url.Parse(f.url)
+	if err != nil {
+		return nil, err
+	}
+	if f.Action == "" {
+		return base, nil
+	}
+	a, err := url.Parse(f.Action)
+	if err != nil {
+		return nil, err
+	}
+	return base.ResolveReference(a), nil
+}
+
+func (r *Response) newForm(n *html.Node) *Form {
+	f := &Form{
+		Action:  attr(n, "action"),
+		Method:  strings.ToUpper(attr(n, "method")),
+		Enctype: strings.ToLower(attr(n, "enctype")),
+		Values:  url.Values{},
+		handler: r.handler,
+		url:     r.url,
+	}
+	if f.Method == "" {
+		f.Method = "GET"
+	}
+
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		// Browsers don't submit anything inside a disabled fieldset.
+		if c.Type == html.ElementNode && c.Data == "fieldset" && hasAttr(c, "disabled") {
+			return
+		}
+		if c.Type == html.ElementNode && !hasAttr(c, "disabled") {
+			name := attr(c, "name")
+			switch c.Data {
+			case "input":
+				if name != "" {
+					addInput(f.Values, c, name)
+				}
+			case "textarea":
+				if name != "" {
+					f.Values.Add(name, rawText(c))
+				}
+			case "select":
+				if name != "" {
+					addSelect(f.Values, c, name)
+				}
+				return
+			}
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return f
+}
+
+func addInput(vals url.Values, n *html.Node, name string) {
+	switch strings.ToLower(attr(n, "type")) {
+	case "submit", "button", "image", "reset", "file":
+		return
+	case "checkbox", "radio":
+		if !hasAttr(n, "checked") {
+			return
+		}
+		v, ok := lookupAttr(n, "value")
+		if !ok {
+			v = "on"
+		}
+		vals.Add(name, v)
+		return
+	}
+	vals.Add(name, attr(n, "value"))
+}
+
+func addSelect(vals url.Values, n *html.Node, name string) {
+	opts := findAll(n, "option")
+	var picked []*html.Node
+	for _, o := range opts {
+		if hasAttr(o, "selected") && !optionDisabled(o) {
+			picked = append(picked, o)
+		}
+	}
+	if len(picked) == 0 && !hasAttr(n, "multiple") {
+		// Like browsers, default to the first option that isn't disabled.
+		for _, o := range opts {
+			if !optionDisabled(o) {
+				picked = []*html.Node{o}
+				break
+			}
+		}
+	}
+	if len(picked) > 1 && !hasAttr(n, "multiple") {
+		picked = picked[len(picked)-1:]
+	}
+	for _, o := range picked {
+		v, ok := lookupAttr(o, "value")
+		if !ok {
+			v = textContent(o)
+		}
+		vals.Add(name, v)
+	}
+}
+
+// optionDisabled reports whether the option o, or its optgroup, is
+// disabled.
+func optionDisabled(o *html.Node) bool {
+	if hasAttr(o, "disabled") {
+		return true
+	}
+	p := o.Parent
+	return p != nil && p.Data == "optgroup" && hasAttr(p, "disabled")
+}
+
+// rawText returns the text of n's children without collapsing whitespace.
+func rawText(n *html.Node) string {
+	bb := &strings.Builder{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			bb.WriteString(c.Data)
+		}
+	}
+	return bb.String()
+}
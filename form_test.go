@@ -0,0 +1,180 @@
+package httptest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const widgetForm = `<html><body>
+<form action="/search" class="search"><input type="text" name="q" value="go"></form>
+<form id="new-widget" action="/widgets" method="post">
+	<input type="hidden" name="authenticity_token" value="tok123">
+	<input type="text" name="name" value="">
+	<input type="checkbox" name="featured" checked>
+	<input type="checkbox" name="archived" value="yes">
+	<input type="radio" name="size" value="s">
+	<input type="radio" name="size" value="m" checked>
+	<input type="text" name="ignored" value="x" disabled>
+	<select name="color"><option value="red">Red</option><option value="blue" selected>Blue</option></select>
+	<select name="tags" multiple><option selected>a</option><option>b</option><option selected>c</option></select>
+	<textarea name="notes">some notes</textarea>
+	<input type="submit" name="commit" value="Save">
+</form>
+<form id="upload" action="/widgets/upload" method="post" enctype="multipart/form-data">
+	<input type="hidden" name="Name" value="Foo">
+</form>
+</body></html>`
+
+const editForm = `<html><body>
+<form id="edit-widget" action="update" method="post" data-x="a b">
+	<input type="text" name="name" value="Foo">
+	<select name="size"><option disabled>Pick one</option><option>small</option></select>
+	<fieldset disabled>
+		<legend><input type="text" name="legend" value="x"></legend>
+		<input type="text" name="locked" value="y">
+		<select name="color"><option selected>red</option></select>
+	</fieldset>
+</form>
+</body></html>`
+
+func FormApp() http.Handler {
+	p := &mux{}
+	p.Handle("GET", "/widgets/new", func(res http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(res, widgetForm)
+	})
+	echo := func(res http.ResponseWriter, req *http.Request) {
+		req.ParseMultipartForm(5 * 1024)
+		var keys []string
+		for k := range req.Form {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintln(res, "METHOD:"+req.Method)
+		for _, k := range keys {
+			fmt.Fprintf(res, "%s=%s\n", k, strings.Join(req.Form[k], ","))
+		}
+		if _, h, err := req.FormFile("MyFile"); err == nil {
+			fmt.Fprintln(res, "FILE:"+h.Filename)
+		}
+	}
+	p.Handle("GET", "/widgets/1/edit", func(res http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(res, editForm)
+	})
+	p.Handle("POST", "/widgets", echo)
+	p.Handle("POST", "/widgets/upload", echo)
+	p.Handle("GET", "/search", echo)
+	return p
+}
+
+func Test_Form_Values(t *testing.T) {
+	r := require.New(t)
+	w := New(FormApp())
+
+	res := w.HTML("/widgets/new").Get()
+	f, err := res.Form("#new-widget")
+	r.NoError(err)
+	r.Equal("POST", f.Method)
+	r.Equal("/widgets", f.Action)
+	r.Equal("tok123", f.Values.Get("authenticity_token"))
+	r.Equal("on", f.Values.Get("featured"))
+	r.Equal("", f.Values.Get("archived"))
+	r.Equal("m", f.Values.Get("size"))
+	r.Equal("blue", f.Values.Get("color"))
+	r.Equal([]string{"a", "c"}, f.Values["tags"])
+	r.Equal("some notes", f.Values.Get("notes"))
+	r.NotContains(f.Values, "ignored")
+	r.NotContains(f.Values, "commit")
+}
+
+func Test_Form_Submit(t *testing.T) {
+	r := require.New(t)
+	w := New(FormApp())
+
+	res := w.HTML("/widgets/new").Get()
+	f, err := res.Form("/widgets")
+	r.NoError(err)
+
+	res, err = f.Set("name", "Mark").Submit()
+	r.NoError(err)
+	body := res.Body.String()
+	r.Contains(body, "METHOD:POST")
+	r.Contains(body, "authenticity_token=tok123\n")
+	r.Contains(body, "name=Mark\n")
+	r.Contains(body, "tags=a,c\n")
+}
+
+func Test_Form_Submit_Get(t *testing.T) {
+	r := require.New(t)
+	w := New(FormApp())
+
+	res := w.HTML("/widgets/new").Get()
+	f, err := res.Form("form.search")
+	r.NoError(err)
+	r.Equal("GET", f.Method)
+
+	res, err = f.Submit()
+	r.NoError(err)
+	r.Contains(res.Body.String(), "METHOD:GET")
+	r.Contains(res.Body.String(), "q=go\n")
+}
+
+func Test_Form_Submit_Multipart(t *testing.T) {
+	r := require.New(t)
+	w := New(FormApp())
+
+	res := w.HTML("/widgets/new").Get()
+	f, err := res.Form("#upload")
+	r.NoError(err)
+
+	res, err = f.Submit(File{
+		ParamName: "MyFile",
+		FileName:  "foo.go",
+		Reader:    strings.NewReader("package foo"),
+	})
+	r.NoError(err)
+	r.Contains(res.Body.String(), "Name=Foo\n")
+	r.Contains(res.Body.String(), "FILE:foo.go")
+}
+
+func Test_Form_Missing(t *testing.T) {
+	r := require.New(t)
+	w := New(FormApp())
+
+	res := w.HTML("/widgets/new").Get()
+	_, err := res.Form("#nope")
+	r.Error(err)
+}
+
+func Test_Form_By_ID_And_Relative_Action(t *testing.T) {
+	r := require.New(t)
+	w := New(FormApp())
+
+	res := w.HTML("/widgets/new").Get()
+	f, err := res.Form("new-widget")
+	r.NoError(err)
+	r.Equal("/widgets", f.Action)
+
+	res = w.HTML("/widgets/1/edit").Get()
+	for _, sel := range []string{"edit-widget", "update", "/widgets/1/update"} {
+		f, err = res.Form(sel)
+		r.NoError(err, sel)
+		r.Equal("update", f.Action)
+	}
+	r.Equal("Foo", f.Values.Get("name"))
+	r.NotContains(f.Values, "legend")
+	r.NotContains(f.Values, "locked")
+	r.NotContains(f.Values, "color")
+	r.Equal("small", f.Values.Get("size"))
+
+	f, err = res.Form(`form[data-x="a b"]`)
+	r.NoError(err)
+	r.Equal("update", f.Action)
+	doc, err := res.parseHTML()
+	r.NoError(err)
+	r.Len(findAll(doc, `form[data-x='a b'] select option[disabled]`), 1)
+}
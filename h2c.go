@@ -0,0 +1,27 @@
+//go:build go1.24
+// +build go1.24
+
+package httptest
+
+import "net/http"
+
+// NewH2CServer starts a cleartext server for h that accepts HTTP/2 with
+// prior knowledge (h2c) as well as HTTP/1.1. Use H2CClient to talk
+// HTTP/2 to it. It needs Go 1.24 or later.
+func NewH2CServer(h http.Handler) *Server {
+	s := NewUnstartedServer(h)
+	s.Config.Protocols = &http.Protocols{}
+	s.Config.Protocols.SetHTTP1(true)
+	s.Config.Protocols.SetUnencryptedHTTP2(true)
+	s.Start()
+	return s
+}
+
+// H2CClient returns a client that sends HTTP/2 over cleartext
+// connections, for servers started with NewH2CServer.
+func H2CClient() *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Protocols = &http.Protocols{}
+	t.Protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: t}
+}
@@ -0,0 +1,37 @@
+//go:build go1.24
+// +build go1.24
+
+package httptest
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_H2C_Server(t *testing.T) {
+	r := require.New(t)
+	s := NewH2CServer(ProtoApp())
+	defer s.Close()
+
+	res, err := H2CClient().Get(s.URL + "/proto")
+	r.NoError(err)
+	b, err := ioutil.ReadAll(res.Body)
+	r.NoError(err)
+	res.Body.Close()
+	r.Equal("HTTP/2.0 2", string(b))
+	r.Equal("HTTP/2.0", res.Trailer.Get("X-Proto"))
+
+	res, err = s.Client().Get(s.URL + "/proto")
+	r.NoError(err)
+	b, err = ioutil.ReadAll(res.Body)
+	r.NoError(err)
+	res.Body.Close()
+	r.Equal("HTTP/1.1 1", string(b))
+
+	w, err := NewRemote(s.URL)
+	r.NoError(err)
+	w.Handler.(*Remote).Client = H2CClient()
+	r.Equal("HTTP/2.0 2", w.HTML("/proto").Get().Body.String())
+}
@@ -0,0 +1,227 @@
+package httptest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// HAREnv is the environment variable that, when set, makes RecordHAR
+// write the HAR file even if the test passed.
+const HAREnv = "HTTPTEST_HAR"
+
+// HAR records requests and responses in HTTP Archive 1.2 format, which
+// browser devtools and other HAR viewers can open.
+type HAR struct {
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// RecordHAR starts recording everything sent through the Handler. When t
+// finishes the HAR is written to path if t failed or HTTPTEST_HAR is set.
+func (w *Handler) RecordHAR(t testing.TB, path string) *HAR {
+	h := &HAR{}
+	w.HAR = h
+	t.Cleanup(func() {
+		if !t.Failed() && os.Getenv(HAREnv) == "" {
+			return
+		}
+		if err := h.WriteFile(path); err != nil {
+			t.Errorf("could not write HAR file %s: %s", path, err)
+			return
+		}
+		t.Logf("wrote HAR file %s", path)
+	})
+	return h
+}
+
+// Len returns the number of recorded entries.
+func (h *HAR) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.entries)
+}
+
+// WriteFile writes the HAR to path, creating its directory if needed.
+func (h *HAR) WriteFile(path string) error {
+	b, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+func (h *HAR) MarshalJSON() ([]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entries := h.entries
+	if entries == nil {
+		entries = []harEntry{}
+	}
+	return json.Marshal(map[string]interface{}{
+		"log": map[string]interface{}{
+			"version": "1.2",
+			"creator": map[string]string{"name": "github.com/gobuffalo/httptest", "version": Version},
+			"pages":   []interface{}{},
+			"entries": entries,
+		},
+	})
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+func (h *HAR) add(w *Handler, req *http.Request, body []byte, res *Response, start time.Time, d time.Duration) {
+	u := w.absoluteURL(req)
+
+	proto := req.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	hreq := harRequest{
+		Method:      req.Method,
+		URL:         u.String(),
+		HTTPVersion: proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(req.Header),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	for _, c := range req.Cookies() {
+		hreq.Cookies = append(hreq.Cookies, harNameValue{c.Name, c.Value})
+	}
+	for k, vs := range u.Query() {
+		for _, v := range vs {
+			hreq.QueryString = append(hreq.QueryString, harNameValue{k, v})
+		}
+	}
+	if len(body) > 0 {
+		hreq.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+	}
+
+	result := res.Result()
+	hres := harResponse{
+		Status:      res.Code,
+		StatusText:  http.StatusText(res.Code),
+		HTTPVersion: proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(res.Header()),
+		Content: harContent{
+			Size:     res.Body.Len(),
+			MimeType: res.Header().Get("Content-Type"),
+			Text:     res.Body.String(),
+		},
+		RedirectURL: res.Header().Get("Location"),
+		HeadersSize: -1,
+		BodySize:    res.Body.Len(),
+	}
+	for _, c := range result.Cookies() {
+		hres.Cookies = append(hres.Cookies, harNameValue{c.Name, c.Value})
+	}
+
+	ms := float64(d) / float64(time.Millisecond)
+	h.mu.Lock()
+	h.entries = append(h.entries, harEntry{
+		StartedDateTime: start.Format("2006-01-02T15:04:05.000Z07:00"),
+		Time:            ms,
+		Request:         hreq,
+		Response:        hres,
+		Timings:         harTimings{Wait: ms},
+	})
+	h.mu.Unlock()
+}
+
+// absoluteURL returns the URL of req with a scheme and host, taken from
+// the request or the Handler's Host, for requests made with just a path.
+// Requests through a Remote get the URL they were sent to.
+func (w *Handler) absoluteURL(req *http.Request) *url.URL {
+	if w != nil {
+		if rm, ok := w.Handler.(*Remote); ok {
+			return rm.target(req.URL)
+		}
+	}
+	u := *req.URL
+	if u.Host == "" {
+		u.Scheme = "http"
+		u.Host = req.Host
+		if u.Host == "" && w != nil {
+			u.Host = w.Host
+		}
+		if u.Host == "" {
+			u.Host = "localhost"
+		}
+	}
+	return &u
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	hs := []harNameValue{}
+	for k, vs := range h {
+		for _, v := range vs {
+			hs = append(hs, harNameValue{k, v})
+		}
+	}
+	return hs
+}
@@ -0,0 +1,107 @@
+package httptest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type harFile struct {
+	Log struct {
+		Version string     `json:"version"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+func readHAR(t *testing.T, path string) harFile {
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	hf := harFile{}
+	require.NoError(t, json.Unmarshal(b, &hf))
+	return hf
+}
+
+func Test_HAR_Records_Interactions(t *testing.T) {
+	r := require.New(t)
+	path := filepath.Join(t.TempDir(), "app.har")
+
+	w := New(App())
+	h := &HAR{}
+	w.HAR = h
+
+	w.HTML("/sessions/set").Post(User{Name: "mark"})
+	w.HTML("/sessions/get?x=1").Get()
+	_, err := w.HTML("/up").MultiPartPost(struct{ Name string }{"Foo"}, File{ParamName: "MyFile", FileName: "foo.go", Reader: strings.NewReader("package foo")})
+	r.NoError(err)
+
+	j := New(JSONApp())
+	j.HAR = h
+	j.JSON("/post").Post(jBody{Name: "Mark"})
+
+	x := New(XMLApp())
+	x.HAR = h
+	x.XML("/get").Get()
+
+	r.Equal(5, h.Len())
+	r.NoError(h.WriteFile(path))
+
+	hf := readHAR(t, path)
+	r.Equal("1.2", hf.Log.Version)
+	es := hf.Log.Entries
+	r.Len(es, 5)
+
+	r.Equal("POST", es[0].Request.Method)
+	r.Equal("http://localhost/sessions/set", es[0].Request.URL)
+	r.Equal("name=mark", es[0].Request.PostData.Text)
+	r.Len(es[0].Response.Cookies, 1)
+	r.Equal("my-session", es[0].Response.Cookies[0].Name)
+
+	r.Equal("GET", es[1].Request.Method)
+	r.Equal([]harNameValue{{"x", "1"}}, es[1].Request.QueryString)
+	r.Equal("my-session", es[1].Request.Cookies[0].Name)
+	r.Equal("NAME:mark", es[1].Response.Content.Text)
+
+	r.Contains(es[2].Request.PostData.MimeType, "multipart/form-data")
+	r.Contains(es[2].Request.PostData.Text, "package foo")
+	r.Equal("Foo\nfoo.go\n", es[2].Response.Content.Text)
+
+	r.Contains(es[3].Request.PostData.Text, `"name":"Mark"`)
+	r.Contains(es[3].Response.Content.Text, `"name":"Mark"`)
+
+	r.Equal(201, es[4].Response.Status)
+	r.Equal("Created", es[4].Response.StatusText)
+	r.Contains(es[4].Response.Content.Text, "Hello from Get!")
+	for _, e := range es {
+		r.NotEmpty(e.StartedDateTime)
+		r.Equal(e.Time, e.Timings.Wait)
+	}
+}
+
+func Test_RecordHAR_Env(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+
+	t.Run("passing", func(st *testing.T) {
+		w := New(App())
+		w.RecordHAR(st, filepath.Join(dir, "passing.har"))
+		w.HTML("/get").Get()
+	})
+	_, err := os.Stat(filepath.Join(dir, "passing.har"))
+	r.True(os.IsNotExist(err))
+
+	os.Setenv(HAREnv, "1")
+	defer os.Unsetenv(HAREnv)
+	t.Run("env", func(st *testing.T) {
+		w := New(App())
+		w.RecordHAR(st, filepath.Join(dir, "env", "env.har"))
+		w.HTML("/get").Get()
+	})
+	hf := readHAR(t, filepath.Join(dir, "env", "env.har"))
+	r.Len(hf.Log.Entries, 1)
+	r.Equal("METHOD:GET\nHello from Get!", hf.Log.Entries[0].Response.Content.Text)
+}
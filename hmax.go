@@ -0,0 +1,121 @@
+package httptest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"net/http"
+
+	"github.com/gobuffalo/httptest/internal/takeon/github.com/markbates/hmax"
+)
+
+// HmaxConfig configures how requests are signed with an HMAC of their
+// body. Only Secret is required; the defaults match HmaxSecret, an
+// `X-Signature` header holding a base64 encoded SHA-256 HMAC.
+type HmaxConfig struct {
+	Secret string
+	// Header is the header the signature is sent in.
+	Header string
+	// Hasher is the hash used for the HMAC, e.g. sha1.New.
+	Hasher func() hash.Hash
+	// Encode turns the MAC into text, e.g. hex.EncodeToString.
+	Encode func([]byte) string
+	// Prefix is prepended to the signature, e.g. "sha256=".
+	Prefix string
+	// RejectStatus and RejectBody are written by Middleware when a
+	// request's signature is missing or wrong. They default to 401 and
+	// "invalid signature".
+	RejectStatus int
+	RejectBody   string
+}
+
+func (c HmaxConfig) hmax() hmax.HMAX {
+	h := hmax.HMAX{
+		Header: c.Header,
+		Hasher: c.Hasher,
+		Secret: []byte(c.Secret),
+		Encode: c.Encode,
+		Prefix: c.Prefix,
+	}
+	if h.Header == "" {
+		h.Header = "X-Signature"
+	}
+	if h.Hasher == nil {
+		h.Hasher = sha256.New
+	}
+	return h
+}
+
+// signRequest signs req with SigV4 if set, then with Canonical, Hmax,
+// or HmaxSecret with the default settings, in that order of preference.
+func (w *Handler) signRequest(req *http.Request) error {
+	if w.SigV4 != nil {
+		if err := w.SigV4.SignRequest(req); err != nil {
+			return err
+		}
+	}
+	if w.Canonical != nil {
+		return w.Canonical.SignRequest(req)
+	}
+	if w.Hmax != nil {
+		return w.Hmax.hmax().SignRequest(req)
+	}
+	if w.HmaxSecret != "" {
+		return HmaxConfig{Secret: w.HmaxSecret}.hmax().SignRequest(req)
+	}
+	return nil
+}
+
+// Sign returns the signature of message.
+func (c HmaxConfig) Sign(message []byte) string {
+	return c.hmax().Sign(message)
+}
+
+// VerifyRequest checks the signature header of req against its body.
+func (c HmaxConfig) VerifyRequest(req *http.Request) error {
+	h := c.hmax()
+	if req.Header.Get(h.Header) == "" {
+		return fmt.Errorf("missing %s header", h.Header)
+	}
+	_, err := h.VerifyRequest(req)
+	return err
+}
+
+// VerifyResponse checks the signature header of res against its body,
+// for APIs that sign their replies.
+func (c HmaxConfig) VerifyResponse(res *Response) error {
+	h := c.hmax()
+	sig := res.Header().Get(h.Header)
+	if sig == "" {
+		return fmt.Errorf("missing %s header", h.Header)
+	}
+	_, err := h.Verify(sig, res.Body.Bytes())
+	return err
+}
+
+// Middleware wraps next, rejecting requests whose signature is missing
+// or doesn't match their body with RejectStatus and RejectBody.
+func (c HmaxConfig) Middleware(next http.Handler) http.Handler {
+	return c.reject(c.VerifyRequest, next)
+}
+
+// reject wraps next, answering requests that fail verify with
+// RejectStatus and RejectBody.
+func (c HmaxConfig) reject(verify func(*http.Request) error, next http.Handler) http.Handler {
+	status := c.RejectStatus
+	if status == 0 {
+		status = http.StatusUnauthorized
+	}
+	body := c.RejectBody
+	if body == "" {
+		body = "invalid signature"
+	}
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if err := verify(req); err != nil {
+			res.WriteHeader(status)
+			fmt.Fprint(res, body)
+			return
+		}
+		next.ServeHTTP(res, req)
+	})
+}
@@ -0,0 +1,129 @@
+package httptest
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func SignedApp() http.Handler {
+	p := &mux{}
+	p.Handle("POST", "/hook", func(res http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		fmt.Fprintln(res, "X-Signature:"+req.Header.Get("X-Signature"))
+		fmt.Fprintln(res, "X-Hub-Signature:"+req.Header.Get("X-Hub-Signature"))
+		fmt.Fprint(res, "BODY:"+string(b))
+	})
+	return p
+}
+
+func Test_HmaxSecret(t *testing.T) {
+	r := require.New(t)
+	w := New(SignedApp())
+	w.HmaxSecret = "secret"
+
+	res := w.JSON("/hook").Post(User{Name: "Mark"})
+
+	hm := hmac.New(sha256.New, []byte("secret"))
+	hm.Write([]byte(`{"Name":"Mark"}`))
+	r.Contains(res.Body.String(), "X-Signature:"+base64.StdEncoding.EncodeToString(hm.Sum(nil)))
+}
+
+func Test_Hmax_Config(t *testing.T) {
+	r := require.New(t)
+	w := New(SignedApp())
+	w.Hmax = &HmaxConfig{
+		Secret: "secret",
+		Header: "X-Hub-Signature",
+		Hasher: sha1.New,
+		Encode: hex.EncodeToString,
+		Prefix: "sha1=",
+	}
+
+	res := w.JSON("/hook").Post(User{Name: "Mark"})
+
+	hm := hmac.New(sha1.New, []byte("secret"))
+	hm.Write([]byte(`{"Name":"Mark"}`))
+	r.Contains(res.Body.String(), "X-Hub-Signature:sha1="+hex.EncodeToString(hm.Sum(nil)))
+	r.Contains(res.Body.String(), "X-Signature:\n")
+	r.Contains(res.Body.String(), `BODY:{"Name":"Mark"}`)
+}
+
+func Test_Hmax_Parallel_Secrets(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		secret := fmt.Sprintf("secret-%d", i)
+		t.Run(secret, func(st *testing.T) {
+			st.Parallel()
+			r := require.New(st)
+			w := New(SignedApp())
+			w.HmaxSecret = secret
+
+			res := w.JSON("/hook").Post(User{Name: "Mark"})
+
+			hm := hmac.New(sha256.New, []byte(secret))
+			hm.Write([]byte(`{"Name":"Mark"}`))
+			r.Contains(res.Body.String(), "X-Signature:"+base64.StdEncoding.EncodeToString(hm.Sum(nil)))
+		})
+	}
+}
+
+func Test_Hmax_Middleware(t *testing.T) {
+	r := require.New(t)
+	c := HmaxConfig{Secret: "secret", RejectStatus: 403, RejectBody: "go away"}
+
+	w := New(c.Middleware(SignedApp()))
+	res := w.JSON("/hook").Post(User{Name: "Mark"})
+	r.Equal(403, res.Code)
+	r.Equal("go away", res.Body.String())
+
+	w.HmaxSecret = "wrong"
+	res = w.JSON("/hook").Post(User{Name: "Mark"})
+	r.Equal(403, res.Code)
+
+	w.Hmax = &c
+	res = w.JSON("/hook").Post(User{Name: "Mark"})
+	r.Equal(200, res.Code)
+	r.Contains(res.Body.String(), `BODY:{"Name":"Mark"}`)
+}
+
+func Test_Hmax_Middleware_Defaults(t *testing.T) {
+	r := require.New(t)
+	c := HmaxConfig{Secret: "secret"}
+
+	w := New(c.Middleware(SignedApp()))
+	res := w.JSON("/hook").Post(User{Name: "Mark"})
+	r.Equal(401, res.Code)
+	r.Equal("invalid signature", res.Body.String())
+}
+
+func Test_Hmax_VerifyResponse(t *testing.T) {
+	r := require.New(t)
+	c := HmaxConfig{Secret: "secret", Header: "X-Reply-Signature"}
+
+	p := &mux{}
+	p.Handle("GET", "/signed", func(res http.ResponseWriter, req *http.Request) {
+		body := []byte(`{"ok":true}`)
+		res.Header().Set("X-Reply-Signature", c.Sign(body))
+		res.Write(body)
+	})
+	p.Handle("GET", "/tampered", func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("X-Reply-Signature", c.Sign([]byte(`{"ok":true}`)))
+		res.Write([]byte(`{"ok":false}`))
+	})
+	p.Handle("GET", "/unsigned", func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte(`{"ok":true}`))
+	})
+	w := New(p)
+
+	r.NoError(c.VerifyResponse(w.JSON("/signed").Get().Response))
+	r.Error(c.VerifyResponse(w.JSON("/tampered").Get().Response))
+	r.Error(c.VerifyResponse(w.JSON("/unsigned").Get().Response))
+}
@@ -0,0 +1,23 @@
+package httptest
+
+import "net/http"
+
+// NewHTTP2Server starts a TLS server for h with HTTP/2 enabled. The
+// server's Client negotiates HTTP/2, so does NewRemoteServer.
+func NewHTTP2Server(h http.Handler) *Server {
+	s := NewUnstartedServer(h)
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	return s
+}
+
+// applyProto marks in-process requests as HTTP/2 when the Handler's
+// HTTP2 is set.
+func (w *Handler) applyProto(req *http.Request) {
+	if !w.HTTP2 {
+		return
+	}
+	req.Proto = "HTTP/2.0"
+	req.ProtoMajor = 2
+	req.ProtoMinor = 0
+}
@@ -0,0 +1,99 @@
+package httptest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ProtoApp reports the protocol of each request and sends a trailer.
+func ProtoApp() http.Handler {
+	p := &mux{}
+	p.Handle("GET", "/proto", func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Trailer", "X-Proto")
+		fmt.Fprintf(res, "%s %d", req.Proto, req.ProtoMajor)
+		res.Header().Set("X-Proto", req.Proto)
+	})
+	return p
+}
+
+func Test_HTTP2_In_Process(t *testing.T) {
+	r := require.New(t)
+	w := New(ProtoApp())
+	r.Equal("HTTP/1.1 1", w.HTML("/proto").Get().Body.String())
+
+	w.HTTP2 = true
+	w.HAR = &HAR{}
+	res := w.HTML("/proto").Get()
+	r.Equal("HTTP/2.0 2", res.Body.String())
+	r.Equal("HTTP/2.0", res.Result().Trailer.Get("X-Proto"))
+	r.Equal("HTTP/2.0", w.HAR.entries[0].Request.HTTPVersion)
+}
+
+func Test_HTTP2_Server(t *testing.T) {
+	r := require.New(t)
+	s := NewHTTP2Server(ProtoApp())
+	defer s.Close()
+
+	res, err := s.Client().Get(s.URL + "/proto")
+	r.NoError(err)
+	b, err := ioutil.ReadAll(res.Body)
+	r.NoError(err)
+	res.Body.Close()
+	r.Equal(2, res.ProtoMajor)
+	r.Equal("HTTP/2.0 2", string(b))
+	r.Equal("HTTP/2.0", res.Trailer.Get("X-Proto"))
+
+	w := NewRemoteServer(s)
+	r.Equal("HTTP/2.0 2", w.HTML("/proto").Get().Body.String())
+}
+
+func Test_HTTP2_Multiplexing(t *testing.T) {
+	r := require.New(t)
+	const n = 5
+
+	var mu sync.Mutex
+	addrs := map[string]bool{}
+	var arrived sync.WaitGroup
+	arrived.Add(n)
+	s := NewHTTP2Server(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		addrs[req.RemoteAddr] = true
+		mu.Unlock()
+		// Every request waits for the others, so they must be in flight
+		// at the same time.
+		arrived.Done()
+		arrived.Wait()
+	}))
+	defer s.Close()
+
+	c := s.Client()
+	c.Timeout = 5 * time.Second
+	c.Transport.(*http.Transport).MaxConnsPerHost = 1
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := c.Get(s.URL)
+			if err != nil {
+				errs <- err
+				return
+			}
+			res.Body.Close()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		r.NoError(err)
+	}
+	r.Len(addrs, 1)
+}
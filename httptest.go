@@ -4,9 +4,17 @@ Package httptest was formerly known as [https://github.com/markbates/willie](htt
 package httptest
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"time"
 )
 
 // map the std httptest package for ease
@@ -25,11 +33,53 @@ type encodable interface {
 	Encode() string
 }
 
+// Handler sends requests to an http.Handler. It may be shared by
+// parallel tests once its fields are set: the cookies and CSRF token it
+// learns from responses are guarded by a mutex.
 type Handler struct {
 	http.Handler
+	// Cookies is the Cookie header sent with every request. Cookies set
+	// by a response are merged into it by name: a new value replaces the
+	// old one, an expired cookie or one with a negative Max-Age is
+	// removed, and cookies the response doesn't mention are kept.
+	// Domain, Path and Secure are ignored.
 	Cookies    string
 	Headers    map[string]string
 	HmaxSecret string
+	// Hmax, if set, is used to sign requests instead of HmaxSecret.
+	Hmax *HmaxConfig
+	// Canonical, if set, signs requests with timestamps and nonces
+	// instead of Hmax or HmaxSecret.
+	Canonical *Canonical
+	// SigV4, if set, signs requests with AWS Signature Version 4. It
+	// can be used alongside the HMAC signing options.
+	SigV4 *SigV4
+	// ClientCert, if set, is presented as the peer certificate chain in
+	// req.TLS of in-process requests, or to the server by a Remote.
+	ClientCert *tls.Certificate
+	// FollowRedirects makes HTML requests follow redirect responses with
+	// a GET. Host is the host name the handler is served as, so that
+	// absolute redirects back to it are sent in-process.
+	FollowRedirects bool
+	Host            string
+	// HTTP2 makes in-process requests arrive as HTTP/2.0 requests.
+	HTTP2 bool
+	// HAR, if set, records every request and response. See RecordHAR.
+	HAR *HAR
+	// Authenticator, if set, adds credentials to every request that
+	// doesn't set its own.
+	Authenticator Authenticator
+	// BearerToken, if set, is sent as an `Authorization: Bearer` header
+	// on every request. Basic auth set on a request takes precedence.
+	BearerToken string
+	// CSRF enables learning the CSRF token from responses and sending
+	// it with later POST, PUT, PATCH and DELETE requests. The learned
+	// token is kept in CSRFToken, which may also be set directly.
+	CSRF      bool
+	CSRFToken string
+
+	mu        sync.Mutex
+	redirects map[string]*Server
 }
 
 func (w *Handler) HTML(u string, args ...interface{}) *Request {
@@ -71,6 +121,108 @@ func (w *Handler) XML(u string, args ...interface{}) *XML {
 	}
 }
 
+// perform prepares and sends req for an HTML, JSON or XML request to u.
+// If preparing req fails, nothing is sent and the Response records the
+// error.
+func (w *Handler) perform(req *http.Request, u string, headers map[string]string, contentType string, cr credentials) *Response {
+	res := &Response{ResponseRecorder: httptest.NewRecorder(), handler: w, url: u}
+	if err := w.prepare(req, headers, contentType, cr); err != nil {
+		return res.fail(err)
+	}
+	w.serve(res, req, cr)
+	w.mergeCookies(res)
+	w.learnCSRF(res)
+	return res
+}
+
+// prepare applies the CSRF token, headers, Content-Type, if any,
+// cookies and authentication that are sent with req. Headers and cookies
+// that req already has are kept.
+func (w *Handler) prepare(req *http.Request, headers map[string]string, contentType string, cr credentials) error {
+	w.applyCSRF(req, contentType)
+	for key, value := range headers {
+		if req.Header.Get(key) == "" {
+			req.Header.Set(key, value)
+		}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.RequestURI = req.URL.RequestURI()
+	if req.Host == "" {
+		req.Host = req.URL.Host
+	}
+	addCookies(req, w.cookieHeader())
+	w.retarget(req)
+	return w.authenticate(req, cr)
+}
+
+// prepared builds a request for method, u and body and prepares it.
+func (w *Handler) prepared(method, u string, body io.Reader, headers map[string]string, contentType string, cr credentials) (*http.Request, error) {
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.prepare(req, headers, contentType, cr); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// serve sends req to the handler, recording into res. When cr has digest
+// set and the handler answers with a 401 Digest challenge, the request
+// is signed again and retried once with a Digest Authorization header.
+func (w *Handler) serve(res *Response, req *http.Request, cr credentials) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	w.do(res, req, body)
+	if !cr.digest || res.Code != http.StatusUnauthorized {
+		return
+	}
+
+	ch, ok := parseDigestChallenge(res.Header().Values("WWW-Authenticate"))
+	if !ok {
+		return
+	}
+	retry := req.Clone(req.Context())
+	if req.Body != nil {
+		retry.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	cnonce := make([]byte, 16)
+	rand.Read(cnonce)
+	retry.Header.Set("Authorization", ch.authorization(retry.Method, retry.URL.RequestURI(), cr.username, cr.password, hex.EncodeToString(cnonce), 1))
+	if err := w.signRequest(retry); err != nil {
+		res.Err = err
+		return
+	}
+
+	res.ResponseRecorder = httptest.NewRecorder()
+	w.do(res, retry, body)
+}
+
+// do calls ServeHTTP once, adding the exchange to the HAR log if one is
+// being recorded.
+func (w *Handler) do(res *Response, req *http.Request, body []byte) {
+	w.applyTLS(req)
+	w.applyProto(req)
+	start := time.Now()
+	res.req, res.reqBody = req, body
+	if rm, ok := w.Handler.(*Remote); ok {
+		if err := rm.serve(res, req); err != nil {
+			res.fail(err)
+		}
+	} else {
+		w.ServeHTTP(res, req)
+	}
+	if w.HAR != nil {
+		w.HAR.add(w, req, body, res, start, time.Since(start))
+	}
+}
+
 func New(h http.Handler) *Handler {
 	return &Handler{
 		Handler: h,
@@ -5,25 +5,31 @@ import (
 	"net/http"
 )
 
+// defaultHMAX is copied, never mutated, so the package level helpers
+// are safe for concurrent use.
 var defaultHMAX = HMAX{Header: "X-Signature", Hasher: sha256.New}
 
 func Sign(secret, message []byte) string {
-	defaultHMAX.Secret = secret
-	return defaultHMAX.Sign(message)
+	h := defaultHMAX
+	h.Secret = secret
+	return h.Sign(message)
 }
 
 func Verify(signature string, secret, message []byte) bool {
-	defaultHMAX.Secret = secret
-	b, _ := defaultHMAX.Verify(signature, message)
+	h := defaultHMAX
+	h.Secret = secret
+	b, _ := h.Verify(signature, message)
 	return b
 }
 
 func SignRequest(req *http.Request, secret []byte) error {
-	defaultHMAX.Secret = secret
-	return defaultHMAX.SignRequest(req)
+	h := defaultHMAX
+	h.Secret = secret
+	return h.SignRequest(req)
 }
 
 func VerifyRequest(req *http.Request, secret []byte) (bool, error) {
-	defaultHMAX.Secret = secret
-	return defaultHMAX.VerifyRequest(req)
+	h := defaultHMAX
+	h.Secret = secret
+	return h.VerifyRequest(req)
 }
@@ -15,6 +15,11 @@ type HMAX struct {
 	Header string
 	Hasher func() hash.Hash
 	Secret []byte
+	// Encode turns the MAC into the signature text. Defaults to
+	// standard base64.
+	Encode func([]byte) string
+	// Prefix is prepended to the encoded signature, e.g. "sha256=".
+	Prefix string
 }
 
 func New(h string, s []byte) HMAX {
@@ -28,7 +33,11 @@ func New(h string, s []byte) HMAX {
 func (h HMAX) Sign(message []byte) string {
 	hm := hmac.New(h.Hasher, h.Secret)
 	hm.Write(message)
-	return base64.StdEncoding.EncodeToString(hm.Sum(nil))
+	enc := h.Encode
+	if enc == nil {
+		enc = base64.StdEncoding.EncodeToString
+	}
+	return h.Prefix + enc(hm.Sum(nil))
 }
 
 func (h HMAX) Verify(signature string, message []byte) (bool, error) {
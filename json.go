@@ -4,9 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
-	"net/http/httptest"
-
-	"github.com/gobuffalo/httptest/internal/takeon/github.com/markbates/hmax"
 )
 
 type JSON struct {
@@ -15,6 +12,12 @@ type JSON struct {
 	Headers  map[string]string
 	Username string
 	Password string
+	// Digest answers HTTP Digest challenges with Username and Password
+	// instead of sending them as Basic auth.
+	Digest bool
+	// Authenticator, if set, is used instead of Username and Password
+	// and the Handler's Authenticator.
+	Authenticator Authenticator
 }
 
 type JSONResponse struct {
@@ -62,22 +65,17 @@ func (r *JSON) Do(method string, body interface{}) (*JSONResponse, error) {
 	if err != nil {
 		return nil, err
 	}
-	return r.Perform(req), nil
+	res := r.Perform(req)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	return res, nil
 }
 
 func (r *JSON) Perform(req *http.Request) *JSONResponse {
-	if r.handler.HmaxSecret != "" {
-		hmax.SignRequest(req, []byte(r.handler.HmaxSecret))
-	}
-	if r.Username != "" || r.Password != "" {
-		req.SetBasicAuth(r.Username, r.Password)
-	}
-	res := &JSONResponse{&Response{httptest.NewRecorder()}}
-	for key, value := range r.Headers {
-		req.Header.Set(key, value)
-	}
-	req.Header.Set("Cookie", r.handler.Cookies)
-	r.handler.ServeHTTP(res, req)
-	r.handler.Cookies = res.Header().Get("Set-Cookie")
-	return res
+	return &JSONResponse{r.handler.perform(req, r.URL, r.Headers, "", r.credentials())}
+}
+
+func (r *JSON) credentials() credentials {
+	return credentials{r.Authenticator, r.Username, r.Password, r.Digest}
 }
@@ -0,0 +1,138 @@
+package httptest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// JWT builds signed JSON Web Tokens for tests. Alg is one of HS256,
+// RS256 or ES256 and Key is, respectively, a []byte secret, an
+// *rsa.PrivateKey or an *ecdsa.PrivateKey using the P-256 curve.
+type JWT struct {
+	Alg    string
+	Key    interface{}
+	KeyID  string
+	Claims map[string]interface{}
+}
+
+// Sign returns the token signed with Key.
+func (j JWT) Sign() (string, error) {
+	return j.sign(j.Claims)
+}
+
+// Expired returns a signed token whose exp claim is an hour in the past.
+func (j JWT) Expired() (string, error) {
+	c := j.claims()
+	c["exp"] = time.Now().Add(-time.Hour).Unix()
+	return j.sign(c)
+}
+
+// NotYetValid returns a signed token whose nbf claim is an hour in the
+// future.
+func (j JWT) NotYetValid() (string, error) {
+	c := j.claims()
+	c["nbf"] = time.Now().Add(time.Hour).Unix()
+	return j.sign(c)
+}
+
+// BadSignature returns a token whose signature does not match its
+// header and claims.
+func (j JWT) BadSignature() (string, error) {
+	s, err := j.sign(j.Claims)
+	if err != nil {
+		return "", err
+	}
+	i := strings.LastIndexByte(s, '.')
+	sig, err := base64.RawURLEncoding.DecodeString(s[i+1:])
+	if err != nil {
+		return "", err
+	}
+	if len(sig) > 0 {
+		sig[0] ^= 0xff
+	}
+	return s[:i+1] + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (j JWT) claims() map[string]interface{} {
+	c := map[string]interface{}{}
+	for k, v := range j.Claims {
+		c[k] = v
+	}
+	return c
+}
+
+func (j JWT) sign(claims map[string]interface{}) (string, error) {
+	if claims == nil {
+		claims = map[string]interface{}{}
+	}
+	head := map[string]string{"alg": j.Alg, "typ": "JWT"}
+	if j.KeyID != "" {
+		head["kid"] = j.KeyID
+	}
+	hb, err := json.Marshal(head)
+	if err != nil {
+		return "", err
+	}
+	cb, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(cb)
+
+	sum := sha256.Sum256([]byte(input))
+	var sig []byte
+	switch j.Alg {
+	case "HS256":
+		key, ok := j.Key.([]byte)
+		if !ok {
+			return "", fmt.Errorf("HS256 requires a []byte key, got %T", j.Key)
+		}
+		hm := hmac.New(sha256.New, key)
+		hm.Write([]byte(input))
+		sig = hm.Sum(nil)
+	case "RS256":
+		key, ok := j.Key.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("RS256 requires an *rsa.PrivateKey, got %T", j.Key)
+		}
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		if err != nil {
+			return "", err
+		}
+	case "ES256":
+		key, ok := j.Key.(*ecdsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("ES256 requires an *ecdsa.PrivateKey, got %T", j.Key)
+		}
+		if key.Curve != elliptic.P256() {
+			return "", fmt.Errorf("ES256 requires a P-256 key, got %s", key.Curve.Params().Name)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, key, sum[:])
+		if err != nil {
+			return "", err
+		}
+		sig = append(padBytes(r, 32), padBytes(s, 32)...)
+	default:
+		return "", fmt.Errorf("unsupported JWT algorithm %q", j.Alg)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func padBytes(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
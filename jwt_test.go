@@ -0,0 +1,158 @@
+package httptest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// verifyJWT is a minimal verifier used to check the tokens built by JWT.
+func verifyJWT(token string, key interface{}) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	input := parts[0] + "." + parts[1]
+	sum := sha256.Sum256([]byte(input))
+
+	switch k := key.(type) {
+	case []byte:
+		hm := hmac.New(sha256.New, k)
+		hm.Write([]byte(input))
+		if !hmac.Equal(sig, hm.Sum(nil)) {
+			return nil, fmt.Errorf("bad signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig); err != nil {
+			return nil, err
+		}
+	case *ecdsa.PublicKey:
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(k, sum[:], r, s) {
+			return nil, fmt.Errorf("bad signature")
+		}
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, err
+	}
+	now := float64(time.Now().Unix())
+	if exp, ok := claims["exp"].(float64); ok && now > exp {
+		return nil, fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+		return nil, fmt.Errorf("token not yet valid")
+	}
+	return claims, nil
+}
+
+func Test_JWT_Algorithms(t *testing.T) {
+	r := require.New(t)
+
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	r.NoError(err)
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	r.NoError(err)
+
+	table := []struct {
+		alg    string
+		key    interface{}
+		verify interface{}
+	}{
+		{"HS256", []byte("secret"), []byte("secret")},
+		{"RS256", rk, &rk.PublicKey},
+		{"ES256", ek, &ek.PublicKey},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.alg, func(st *testing.T) {
+			r := require.New(st)
+			j := JWT{Alg: tt.alg, Key: tt.key, Claims: map[string]interface{}{"sub": "mark"}}
+
+			s, err := j.Sign()
+			r.NoError(err)
+			claims, err := verifyJWT(s, tt.verify)
+			r.NoError(err)
+			r.Equal("mark", claims["sub"])
+
+			s, err = j.Expired()
+			r.NoError(err)
+			_, err = verifyJWT(s, tt.verify)
+			r.EqualError(err, "token expired")
+
+			s, err = j.NotYetValid()
+			r.NoError(err)
+			_, err = verifyJWT(s, tt.verify)
+			r.EqualError(err, "token not yet valid")
+
+			s, err = j.BadSignature()
+			r.NoError(err)
+			_, err = verifyJWT(s, tt.verify)
+			r.Error(err)
+
+			r.NotContains(j.Claims, "exp")
+		})
+	}
+}
+
+func Test_JWT_Wrong_Key(t *testing.T) {
+	r := require.New(t)
+
+	_, err := JWT{Alg: "RS256", Key: []byte("secret")}.Sign()
+	r.Error(err)
+	_, err = JWT{Alg: "none"}.Sign()
+	r.Error(err)
+
+	ek, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	r.NoError(err)
+	_, err = JWT{Alg: "ES256", Key: ek}.Sign()
+	r.EqualError(err, "ES256 requires a P-256 key, got P-384")
+}
+
+func Test_BearerToken(t *testing.T) {
+	r := require.New(t)
+	p := &mux{}
+	p.Handle("GET", "/me", func(res http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(res, req.Header.Get("Authorization"))
+	})
+	w := New(p)
+
+	s, err := JWT{Alg: "HS256", Key: []byte("secret"), Claims: map[string]interface{}{"sub": "mark"}}.Sign()
+	r.NoError(err)
+	w.BearerToken = s
+
+	res := w.HTML("/me").Get()
+	r.Equal("Bearer "+s, res.Body.String())
+
+	jres := w.JSON("/me").Get()
+	r.Equal("Bearer "+s, jres.Body.String())
+
+	req := w.XML("/me")
+	req.Username = "mark"
+	xres := req.Get()
+	r.True(strings.HasPrefix(xres.Body.String(), "Basic "))
+}
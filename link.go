@@ -0,0 +1,54 @@
+package httptest
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/gobuffalo/httptest/internal/takeon/golang.org/x/net/html"
+)
+
+// ClickLink finds an anchor in the HTML body of the response, either by
+// its text or by a CSS selector, and follows it with a GET request
+// through the same Handler. The href is resolved against the URL of
+// the response and the request is sent with a Referer header.
+func (r *Response) ClickLink(textOrSelector string) (*Response, error) {
+	doc, err := r.parseHTML()
+	if err != nil {
+		return nil, err
+	}
+
+	a := findLink(doc, textOrSelector)
+	if a == nil {
+		return nil, fmt.Errorf("could not find link %q", textOrSelector)
+	}
+
+	base, err := url.Parse(r.url)
+	if err != nil {
+		return nil, err
+	}
+	href, err := url.Parse(attr(a, "href"))
+	if err != nil {
+		return nil, err
+	}
+	u := base.ResolveReference(href)
+	u.Fragment = ""
+
+	req := r.handler.HTML("%s", u.String())
+	req.Headers["Referer"] = r.url
+	return req.Get(), nil
+}
+
+func findLink(doc *html.Node, textOrSelector string) *html.Node {
+	links := findAll(doc, "a[href]")
+	for _, a := range links {
+		if textContent(a) == textOrSelector {
+			return a
+		}
+	}
+	for _, a := range findAll(doc, textOrSelector) {
+		if a.Data == "a" && hasAttr(a, "href") {
+			return a
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package httptest
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func LinkApp() http.Handler {
+	p := &mux{}
+	p.Handle("GET", "/dashboard", func(res http.ResponseWriter, req *http.Request) {
+		http.SetCookie(res, &http.Cookie{Name: "seen", Value: "dashboard"})
+		fmt.Fprint(res, `<html><body><nav>
+<a href="/widgets/1/edit">Edit</a>
+<a class="logout" href="logout#top">Sign out</a>
+</nav></body></html>`)
+	})
+	show := func(res http.ResponseWriter, req *http.Request) {
+		c, _ := req.Cookie("seen")
+		fmt.Fprintln(res, "PATH:"+req.URL.Path)
+		fmt.Fprintln(res, "REFERER:"+req.Referer())
+		if c != nil {
+			fmt.Fprintln(res, "COOKIE:"+c.Value)
+		}
+	}
+	p.Handle("GET", "/widgets/1/edit", show)
+	p.Handle("GET", "/logout", show)
+	return p
+}
+
+func Test_ClickLink_Text(t *testing.T) {
+	r := require.New(t)
+	w := New(LinkApp())
+
+	res := w.HTML("/dashboard").Get()
+	res, err := res.ClickLink("Edit")
+	r.NoError(err)
+	r.Contains(res.Body.String(), "PATH:/widgets/1/edit")
+	r.Contains(res.Body.String(), "REFERER:/dashboard")
+	r.Contains(res.Body.String(), "COOKIE:dashboard")
+}
+
+func Test_ClickLink_Selector(t *testing.T) {
+	r := require.New(t)
+	w := New(LinkApp())
+
+	res := w.HTML("/dashboard").Get()
+	res, err := res.ClickLink("nav a.logout")
+	r.NoError(err)
+	r.Contains(res.Body.String(), "PATH:/logout")
+}
+
+func Test_ClickLink_Missing(t *testing.T) {
+	r := require.New(t)
+	w := New(LinkApp())
+
+	res := w.HTML("/dashboard").Get()
+	_, err := res.ClickLink("Delete")
+	r.Error(err)
+}
@@ -0,0 +1,211 @@
+package httptest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxErrorSamples is the number of error messages kept by Load.
+const maxErrorSamples = 10
+
+// LoadOptions control how Load drives an endpoint. Load stops after
+// Count requests or once Duration has passed, whichever comes first; at
+// least one of them must be set.
+type LoadOptions struct {
+	// Concurrency is the number of requests in flight. It defaults to 1.
+	Concurrency int
+	Duration    time.Duration
+	Count       int
+	// RatePerSecond caps the number of requests started per second
+	// across all workers. Zero means as fast as possible.
+	RatePerSecond float64
+}
+
+// LoadResult holds the statistics gathered by Load. Errors counts the
+// requests that failed to send or got a 5xx response.
+type LoadResult struct {
+	Requests     int
+	Elapsed      time.Duration
+	Throughput   float64
+	P50          time.Duration
+	P90          time.Duration
+	P99          time.Duration
+	Max          time.Duration
+	Statuses     map[int]int
+	Errors       int
+	ErrorSamples []string
+}
+
+func (r *LoadResult) String() string {
+	codes := make([]int, 0, len(r.Statuses))
+	for c := range r.Statuses {
+		codes = append(codes, c)
+	}
+	sort.Ints(codes)
+	statuses := make([]string, len(codes))
+	for i, c := range codes {
+		statuses[i] = fmt.Sprintf("%d:%d", c, r.Statuses[c])
+	}
+	return fmt.Sprintf("%d requests in %s (%.1f/s) p50=%s p90=%s p99=%s max=%s statuses=[%s] errors=%d",
+		r.Requests, r.Elapsed, r.Throughput, r.P50, r.P90, r.P99, r.Max, strings.Join(statuses, " "), r.Errors)
+}
+
+// Load sends copies of req to the handler, or to its Remote, from
+// opts.Concurrency goroutines and reports latencies, throughput and
+// status codes. Requests go through RoundTrip, so the Handler's headers,
+// cookies and authentication are applied to each.
+func (w *Handler) Load(req *http.Request, opts LoadOptions) (*LoadResult, error) {
+	if opts.Count <= 0 && opts.Duration <= 0 {
+		return nil, fmt.Errorf("load needs a Count or a Duration")
+	}
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var deadline <-chan time.Time
+	if opts.Duration > 0 {
+		timer := time.NewTimer(opts.Duration)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	var ticks <-chan time.Time
+	if opts.RatePerSecond > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.RatePerSecond))
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	done := make(chan struct{})
+	var stop sync.Once
+	go func() {
+		select {
+		case <-deadline:
+			stop.Do(func() { close(done) })
+		case <-done:
+		}
+	}()
+
+	var sent int64
+	// next reports whether another request may be sent, waiting for the
+	// rate limit if there is one.
+	next := func() bool {
+		if opts.Count > 0 && atomic.AddInt64(&sent, 1) > int64(opts.Count) {
+			return false
+		}
+		if ticks != nil {
+			select {
+			case <-ticks:
+			case <-done:
+				return false
+			}
+		}
+		select {
+		case <-done:
+			return false
+		default:
+			return true
+		}
+	}
+
+	type stats struct {
+		latencies []time.Duration
+		statuses  map[int]int
+		errors    int
+		samples   []string
+	}
+	all := make([]stats, workers)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(s *stats) {
+			defer wg.Done()
+			s.statuses = map[int]int{}
+			for next() {
+				r := req.Clone(req.Context())
+				if body != nil {
+					r.Body = ioutil.NopCloser(bytes.NewReader(body))
+				}
+				began := time.Now()
+				res, err := w.RoundTrip(r)
+				if err == nil {
+					io.Copy(ioutil.Discard, res.Body)
+					res.Body.Close()
+				}
+				s.latencies = append(s.latencies, time.Since(began))
+
+				var msg string
+				switch {
+				case err != nil:
+					msg = err.Error()
+				case res.StatusCode >= 500:
+					s.statuses[res.StatusCode]++
+					msg = res.Status
+				default:
+					s.statuses[res.StatusCode]++
+				}
+				if msg != "" {
+					s.errors++
+					if len(s.samples) < maxErrorSamples {
+						s.samples = append(s.samples, msg)
+					}
+				}
+			}
+		}(&all[i])
+	}
+	wg.Wait()
+	stop.Do(func() { close(done) })
+
+	res := &LoadResult{Elapsed: time.Since(start), Statuses: map[int]int{}}
+	var latencies []time.Duration
+	for _, s := range all {
+		latencies = append(latencies, s.latencies...)
+		for c, n := range s.statuses {
+			res.Statuses[c] += n
+		}
+		res.Errors += s.errors
+		for _, m := range s.samples {
+			if len(res.ErrorSamples) < maxErrorSamples {
+				res.ErrorSamples = append(res.ErrorSamples, m)
+			}
+		}
+	}
+	res.Requests = len(latencies)
+	if res.Requests == 0 {
+		return res, nil
+	}
+	res.Throughput = float64(res.Requests) / res.Elapsed.Seconds()
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	res.P50 = percentile(latencies, 0.50)
+	res.P90 = percentile(latencies, 0.90)
+	res.P99 = percentile(latencies, 0.99)
+	res.Max = latencies[len(latencies)-1]
+	return res, nil
+}
+
+// percentile returns the nearest-rank percentile p of sorted.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
@@ -0,0 +1,86 @@
+package httptest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Load_Count(t *testing.T) {
+	r := require.New(t)
+	w := New(App())
+	req, err := http.NewRequest("GET", "/get", nil)
+	r.NoError(err)
+
+	res, err := w.Load(req, LoadOptions{Concurrency: 4, Count: 200})
+	r.NoError(err)
+	r.Equal(200, res.Requests)
+	r.Equal(map[int]int{201: 200}, res.Statuses)
+	r.Zero(res.Errors)
+	r.True(res.Throughput > 0)
+	r.True(res.P50 <= res.P90 && res.P90 <= res.P99 && res.P99 <= res.Max)
+	r.Contains(res.String(), "200 requests in ")
+	r.Contains(res.String(), "statuses=[201:200] errors=0")
+}
+
+func Test_Load_Errors(t *testing.T) {
+	r := require.New(t)
+	f := &Faults{Rules: []FaultRule{{Every: 4, Fault: Fault{Status: 500}}}}
+	w := New(f.Handler(App()))
+	req, err := http.NewRequest("POST", "/sessions/set", strings.NewReader("name=mark"))
+	r.NoError(err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := w.Load(req, LoadOptions{Concurrency: 3, Count: 100})
+	r.NoError(err)
+	r.Equal(map[int]int{200: 75, 500: 25}, res.Statuses)
+	r.Equal(25, res.Errors)
+	r.Len(res.ErrorSamples, maxErrorSamples)
+	r.Equal("500 Internal Server Error", res.ErrorSamples[0])
+}
+
+func Test_Load_Duration_Rate(t *testing.T) {
+	r := require.New(t)
+	w := New(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		time.Sleep(2 * time.Millisecond)
+	}))
+	req, err := http.NewRequest("GET", "/", nil)
+	r.NoError(err)
+
+	res, err := w.Load(req, LoadOptions{Concurrency: 4, Duration: 300 * time.Millisecond, RatePerSecond: 50})
+	r.NoError(err)
+	r.True(res.Requests >= 5 && res.Requests <= 16, res.String())
+	r.True(res.P50 >= 2*time.Millisecond, res.String())
+	r.True(res.Elapsed >= 300*time.Millisecond, res.String())
+}
+
+func Test_Load_Remote(t *testing.T) {
+	r := require.New(t)
+	s := NewServer(App())
+	defer s.Close()
+	req, err := http.NewRequest("GET", "/get", nil)
+	r.NoError(err)
+
+	res, err := NewRemoteServer(s).Load(req, LoadOptions{Concurrency: 2, Count: 20})
+	r.NoError(err)
+	r.Equal(map[int]int{201: 20}, res.Statuses)
+
+	s.Close()
+	w, err := NewRemote(s.URL)
+	r.NoError(err)
+	res, err = w.Load(req, LoadOptions{Count: 3})
+	r.NoError(err)
+	r.Equal(3, res.Errors)
+	r.Empty(res.Statuses)
+}
+
+func Test_Load_Options(t *testing.T) {
+	r := require.New(t)
+	req, err := http.NewRequest("GET", "/get", nil)
+	r.NoError(err)
+	_, err = New(App()).Load(req, LoadOptions{Concurrency: 2})
+	r.Error(err)
+}
@@ -0,0 +1,280 @@
+package httptest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCUser is a user an OIDCProvider can sign in. Claims are added to
+// the ID token and userinfo response alongside sub, email and name.
+type OIDCUser struct {
+	Subject string
+	Email   string
+	Name    string
+	Claims  map[string]interface{}
+}
+
+func (u OIDCUser) claims() map[string]interface{} {
+	c := map[string]interface{}{}
+	for k, v := range u.Claims {
+		c[k] = v
+	}
+	c["sub"] = u.Subject
+	if u.Email != "" {
+		c["email"] = u.Email
+		c["email_verified"] = true
+	}
+	if u.Name != "" {
+		c["name"] = u.Name
+	}
+	return c
+}
+
+// OIDCProvider is a local stand-in for an OAuth2 / OpenID Connect
+// identity provider. It serves discovery, authorize, token, userinfo and
+// JWKS endpoints, signs users in without prompting, and records the
+// requests the application made to it.
+//
+// The authorize endpoint signs in the user whose Subject matches the
+// login_hint parameter, or Login if there is none.
+type OIDCProvider struct {
+	*Server
+	ClientID     string
+	ClientSecret string
+	Users        []OIDCUser
+	Login        string
+	Key          *rsa.PrivateKey
+	KeyID        string
+
+	mu             sync.Mutex
+	codes          map[string]oidcGrant
+	tokens         map[string]OIDCUser
+	authorizations []url.Values
+	tokenRequests  []url.Values
+}
+
+type oidcGrant struct {
+	user        OIDCUser
+	clientID    string
+	redirectURI string
+	nonce       string
+}
+
+// NewOIDCProvider starts a provider for the given client. The first user
+// is signed in by default. Close the provider when done.
+func NewOIDCProvider(clientID, clientSecret string, users ...OIDCUser) (*OIDCProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	p := &OIDCProvider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Users:        users,
+		Key:          key,
+		KeyID:        "httptest",
+		codes:        map[string]oidcGrant{},
+		tokens:       map[string]OIDCUser{},
+	}
+	if len(users) > 0 {
+		p.Login = users[0].Subject
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/userinfo", p.userinfo)
+	mux.HandleFunc("/jwks", p.jwks)
+	p.Server = NewServer(mux)
+	return p, nil
+}
+
+// Issuer is the provider's issuer URL, which is also its discovery base.
+func (p *OIDCProvider) Issuer() string {
+	return p.URL
+}
+
+// Authorizations returns the query of every request made to the
+// authorize endpoint.
+func (p *OIDCProvider) Authorizations() []url.Values {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]url.Values{}, p.authorizations...)
+}
+
+// TokenRequests returns the form of every request made to the token
+// endpoint.
+func (p *OIDCProvider) TokenRequests() []url.Values {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]url.Values{}, p.tokenRequests...)
+}
+
+func (p *OIDCProvider) discovery(res http.ResponseWriter, req *http.Request) {
+	writeJSON(res, 200, map[string]interface{}{
+		"issuer":                                p.URL,
+		"authorization_endpoint":                p.URL + "/authorize",
+		"token_endpoint":                        p.URL + "/token",
+		"userinfo_endpoint":                     p.URL + "/userinfo",
+		"jwks_uri":                              p.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+func (p *OIDCProvider) authorize(res http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	p.mu.Lock()
+	p.authorizations = append(p.authorizations, q)
+	p.mu.Unlock()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || q.Get("redirect_uri") == "" {
+		http.Error(res, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("client_id") != p.ClientID {
+		http.Error(res, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+
+	rq := redirect.Query()
+	if q.Get("state") != "" {
+		rq.Set("state", q.Get("state"))
+	}
+	login := q.Get("login_hint")
+	if login == "" {
+		login = p.Login
+	}
+	user, ok := p.user(login)
+	switch {
+	case q.Get("response_type") != "code":
+		rq.Set("error", "unsupported_response_type")
+	case !ok:
+		rq.Set("error", "access_denied")
+	default:
+		code := randomToken()
+		p.mu.Lock()
+		p.codes[code] = oidcGrant{user: user, clientID: q.Get("client_id"), redirectURI: q.Get("redirect_uri"), nonce: q.Get("nonce")}
+		p.mu.Unlock()
+		rq.Set("code", code)
+	}
+	redirect.RawQuery = rq.Encode()
+	http.Redirect(res, req, redirect.String(), http.StatusFound)
+}
+
+func (p *OIDCProvider) token(res http.ResponseWriter, req *http.Request) {
+	req.ParseForm()
+	p.mu.Lock()
+	p.tokenRequests = append(p.tokenRequests, req.PostForm)
+	p.mu.Unlock()
+
+	id, secret, ok := req.BasicAuth()
+	if !ok {
+		id, secret = req.PostFormValue("client_id"), req.PostFormValue("client_secret")
+	}
+	if id != p.ClientID || secret != p.ClientSecret {
+		writeJSON(res, 401, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if req.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(res, 400, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	p.mu.Lock()
+	grant, ok := p.codes[req.PostFormValue("code")]
+	delete(p.codes, req.PostFormValue("code"))
+	p.mu.Unlock()
+	if !ok || grant.clientID != id || grant.redirectURI != req.PostFormValue("redirect_uri") {
+		writeJSON(res, 400, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := grant.user.claims()
+	now := time.Now()
+	claims["iss"] = p.URL
+	claims["aud"] = p.ClientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(time.Hour).Unix()
+	if grant.nonce != "" {
+		claims["nonce"] = grant.nonce
+	}
+	idToken, err := JWT{Alg: "RS256", Key: p.Key, KeyID: p.KeyID, Claims: claims}.Sign()
+	if err != nil {
+		writeJSON(res, 500, map[string]string{"error": err.Error()})
+		return
+	}
+
+	access := randomToken()
+	p.mu.Lock()
+	p.tokens[access] = grant.user
+	p.mu.Unlock()
+	writeJSON(res, 200, map[string]interface{}{
+		"access_token": access,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (p *OIDCProvider) userinfo(res http.ResponseWriter, req *http.Request) {
+	tok := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	p.mu.Lock()
+	user, ok := p.tokens[tok]
+	p.mu.Unlock()
+	if !ok {
+		res.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeJSON(res, 401, map[string]string{"error": "invalid_token"})
+		return
+	}
+	writeJSON(res, 200, user.claims())
+}
+
+func (p *OIDCProvider) jwks(res http.ResponseWriter, req *http.Request) {
+	pub := p.Key.PublicKey
+	writeJSON(res, 200, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": p.KeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *OIDCProvider) user(subject string) (OIDCUser, bool) {
+	for _, u := range p.Users {
+		if u.Subject == subject {
+			return u, true
+		}
+	}
+	return OIDCUser{}, false
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(res http.ResponseWriter, status int, v interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(v)
+}
@@ -0,0 +1,189 @@
+package httptest
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func getJSON(u string, v interface{}) error {
+	res, err := http.Get(u)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// LoginApp signs users in with the provider at issuer using the
+// authorization code flow.
+func LoginApp(issuer string) http.Handler {
+	p := &mux{}
+	p.Handle("GET", "/login", func(res http.ResponseWriter, req *http.Request) {
+		conf := map[string]interface{}{}
+		if err := getJSON(issuer+"/.well-known/openid-configuration", &conf); err != nil {
+			res.WriteHeader(500)
+			return
+		}
+		q := url.Values{}
+		q.Set("client_id", "my-app")
+		q.Set("redirect_uri", "http://app.test/auth/callback")
+		q.Set("response_type", "code")
+		q.Set("scope", "openid email")
+		q.Set("state", "st4te")
+		q.Set("nonce", "n0nce")
+		if h := req.URL.Query().Get("as"); h != "" {
+			q.Set("login_hint", h)
+		}
+		http.Redirect(res, req, conf["authorization_endpoint"].(string)+"?"+q.Encode(), http.StatusFound)
+	})
+	p.Handle("GET", "/auth/callback", func(res http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if q.Get("state") != "st4te" || q.Get("code") == "" {
+			res.WriteHeader(400)
+			fmt.Fprint(res, q.Get("error"))
+			return
+		}
+
+		form := url.Values{}
+		form.Set("grant_type", "authorization_code")
+		form.Set("code", q.Get("code"))
+		form.Set("redirect_uri", "http://app.test/auth/callback")
+		treq, _ := http.NewRequest("POST", issuer+"/token", strings.NewReader(form.Encode()))
+		treq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		treq.SetBasicAuth("my-app", "s3cret")
+		tres, err := http.DefaultClient.Do(treq)
+		if err != nil {
+			res.WriteHeader(500)
+			return
+		}
+		defer tres.Body.Close()
+		tok := map[string]interface{}{}
+		json.NewDecoder(tres.Body).Decode(&tok)
+
+		keys := struct {
+			Keys []map[string]string `json:"keys"`
+		}{}
+		getJSON(issuer+"/jwks", &keys)
+		n, _ := base64.RawURLEncoding.DecodeString(keys.Keys[0]["n"])
+		e, _ := base64.RawURLEncoding.DecodeString(keys.Keys[0]["e"])
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		claims, err := verifyJWT(fmt.Sprint(tok["id_token"]), pub)
+		if err != nil || claims["nonce"] != "n0nce" || claims["aud"] != "my-app" || claims["iss"] != issuer {
+			res.WriteHeader(401)
+			return
+		}
+
+		ureq, _ := http.NewRequest("GET", issuer+"/userinfo", nil)
+		ureq.Header.Set("Authorization", "Bearer "+fmt.Sprint(tok["access_token"]))
+		ures, err := http.DefaultClient.Do(ureq)
+		if err != nil {
+			res.WriteHeader(500)
+			return
+		}
+		defer ures.Body.Close()
+		info := map[string]interface{}{}
+		json.NewDecoder(ures.Body).Decode(&info)
+
+		sess, _ := Store.Get(req, "login-session")
+		sess.Values["email"] = info["email"]
+		sess.Save(req, res)
+		http.Redirect(res, req, "/dashboard", http.StatusFound)
+	})
+	p.Handle("GET", "/dashboard", func(res http.ResponseWriter, req *http.Request) {
+		sess, _ := Store.Get(req, "login-session")
+		fmt.Fprintf(res, "Welcome %v", sess.Values["email"])
+	})
+	return p
+}
+
+func Test_OIDCProvider_Login(t *testing.T) {
+	r := require.New(t)
+	p, err := NewOIDCProvider("my-app", "s3cret",
+		OIDCUser{Subject: "mark", Email: "mark@example.com", Name: "Mark"},
+		OIDCUser{Subject: "jane", Email: "jane@example.com", Claims: map[string]interface{}{"role": "admin"}},
+	)
+	r.NoError(err)
+	defer p.Close()
+
+	w := New(LoginApp(p.Issuer()))
+	w.FollowRedirects = true
+	w.AllowRedirects(p.Server)
+	w.Host = "app.test"
+
+	res := w.HTML("/login").Get()
+	r.Equal(200, res.Code)
+	r.Equal("Welcome mark@example.com", res.Body.String())
+
+	res = w.HTML("/login?as=jane").Get()
+	r.Equal("Welcome jane@example.com", res.Body.String())
+
+	auths := p.Authorizations()
+	r.Len(auths, 2)
+	r.Equal("openid email", auths[0].Get("scope"))
+	r.Equal("http://app.test/auth/callback", auths[0].Get("redirect_uri"))
+	r.Equal("jane", auths[1].Get("login_hint"))
+
+	toks := p.TokenRequests()
+	r.Len(toks, 2)
+	r.Equal("authorization_code", toks[0].Get("grant_type"))
+}
+
+func Test_OIDCProvider_Unknown_User(t *testing.T) {
+	r := require.New(t)
+	p, err := NewOIDCProvider("my-app", "s3cret", OIDCUser{Subject: "mark"})
+	r.NoError(err)
+	defer p.Close()
+
+	w := New(LoginApp(p.Issuer()))
+	w.FollowRedirects = true
+	w.AllowRedirects(p.Server)
+	w.Host = "app.test"
+
+	res := w.HTML("/login?as=nobody").Get()
+	r.Equal(400, res.Code)
+	r.Equal("access_denied", res.Body.String())
+}
+
+func Test_FollowRedirects_Disabled(t *testing.T) {
+	r := require.New(t)
+	p, err := NewOIDCProvider("my-app", "s3cret", OIDCUser{Subject: "mark"})
+	r.NoError(err)
+	defer p.Close()
+
+	w := New(LoginApp(p.Issuer()))
+	res := w.HTML("/login").Get()
+	r.Equal(302, res.Code)
+	r.True(strings.HasPrefix(res.Location(), p.URL+"/authorize?"))
+}
+
+func Test_FollowRedirects_Only_Allowed_Servers(t *testing.T) {
+	r := require.New(t)
+	p, err := NewOIDCProvider("my-app", "s3cret", OIDCUser{Subject: "mark"})
+	r.NoError(err)
+	defer p.Close()
+
+	w := New(LoginApp(p.Issuer()))
+	w.FollowRedirects = true
+	res := w.HTML("/login").Get()
+	r.Equal(302, res.Code)
+	r.NoError(res.Err)
+	r.Empty(p.Authorizations())
+
+	s := NewServer(App())
+	s.Close()
+	w = New(http.RedirectHandler(s.URL+"/get", 302))
+	w.FollowRedirects = true
+	w.AllowRedirects(s)
+	res = w.HTML("/").Get()
+	r.Equal(302, res.Code)
+	r.Error(res.Err)
+}
@@ -0,0 +1,124 @@
+package httptest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+)
+
+// maxRedirects is the number of redirects followed before giving up and
+// returning the last redirect response.
+const maxRedirects = 10
+
+// AllowRedirects lets redirects to servers, such as an OIDCProvider's
+// Server, be followed over the network, so a flow can bounce through
+// them and back. Redirects to any other host are not followed.
+func (w *Handler) AllowRedirects(servers ...*Server) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.redirects == nil {
+		w.redirects = map[string]*Server{}
+	}
+	for _, s := range servers {
+		if u, err := url.Parse(s.URL); err == nil {
+			w.redirects[u.Host] = s
+		}
+	}
+}
+
+func (w *Handler) redirectServer(host string) *Server {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.redirects[host]
+}
+
+// follow follows res, the response to r, while it is a redirect and the
+// Handler has FollowRedirects set. Relative locations, and absolute ones
+// whose host is the Handler's Host, are followed in-process with r's
+// headers and credentials: 307 and 308 redirects repeat r's method and
+// body, the others are followed with a GET. Absolute locations on a
+// server passed to AllowRedirects are fetched from it; any other redirect
+// is returned as is. If fetching fails, the redirect is returned with Err
+// set.
+func (r *Request) follow(res *Response) *Response {
+	w := r.handler
+	if !w.FollowRedirects || r.hops >= maxRedirects {
+		return res
+	}
+	switch res.Code {
+	case 301, 302, 303, 307, 308:
+	default:
+		return res
+	}
+	loc := res.Location()
+	if loc == "" {
+		return res
+	}
+	base, err := url.Parse(res.url)
+	if err != nil {
+		return res
+	}
+	next, err := url.Parse(loc)
+	if err != nil {
+		return res
+	}
+	u := base.ResolveReference(next)
+	u.Fragment = ""
+
+	hop := *r
+	hop.hops++
+	if u.Host != "" && u.Host != w.Host {
+		s := w.redirectServer(u.Host)
+		if s == nil {
+			return res
+		}
+		ext, err := fetch(s.Client(), u.String())
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		ext.handler = w
+		return hop.follow(ext)
+	}
+
+	hop.URL = u.RequestURI()
+	if _, ok := w.Handler.(*Remote); ok && u.Host != "" {
+		// Keep the Remote's base path from being added twice.
+		hop.URL = u.String()
+	}
+	if (res.Code == 307 || res.Code == 308) && res.req != nil {
+		req, err := http.NewRequest(res.req.Method, hop.URL, bytes.NewReader(res.reqBody))
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		return hop.perform(req, res.req.Header.Get("Content-Type"))
+	}
+	return hop.Get()
+}
+
+// fetch GETs u with c, without following redirects, and records the
+// reply as a Response.
+func fetch(c *http.Client, u string) (*Response, error) {
+	cc := *c
+	cc.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	hres, err := cc.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer hres.Body.Close()
+
+	rec := httptest.NewRecorder()
+	for k, v := range hres.Header {
+		rec.Header()[k] = v
+	}
+	rec.WriteHeader(hres.StatusCode)
+	if _, err := io.Copy(rec, hres.Body); err != nil {
+		return nil, err
+	}
+	return &Response{ResponseRecorder: rec, url: u, req: hres.Request}, nil
+}
@@ -0,0 +1,60 @@
+package httptest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func RedirectApp() http.Handler {
+	p := &mux{}
+	p.Handle("GET", "/a", func(res http.ResponseWriter, req *http.Request) {
+		http.Redirect(res, req, "/b", http.StatusFound)
+	})
+	p.Handle("GET", "/b", func(res http.ResponseWriter, req *http.Request) {
+		u, pw, _ := req.BasicAuth()
+		fmt.Fprintf(res, "%s:%s %s", u, pw, req.Header.Get("X-Tenant"))
+	})
+	for _, code := range []int{302, 307, 308} {
+		code := code
+		p.Handle("POST", fmt.Sprintf("/move/%d", code), func(res http.ResponseWriter, req *http.Request) {
+			http.Redirect(res, req, "/moved", code)
+		})
+	}
+	moved := func(res http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(res, req.Method+" ")
+		if req.Body != nil {
+			b, _ := ioutil.ReadAll(req.Body)
+			res.Write(b)
+		}
+	}
+	p.Handle("GET", "/moved", moved)
+	p.Handle("POST", "/moved", moved)
+	return p
+}
+
+func Test_FollowRedirects_Keeps_Credentials(t *testing.T) {
+	r := require.New(t)
+	w := New(RedirectApp())
+	w.FollowRedirects = true
+
+	req := w.HTML("/a")
+	req.SetBasicAuth("mark", "s3cret")
+	req.Headers["X-Tenant"] = "acme"
+	res := req.Get()
+	r.Equal(200, res.Code)
+	r.Equal("mark:s3cret acme", res.Body.String())
+}
+
+func Test_FollowRedirects_307_308_Keep_Method_And_Body(t *testing.T) {
+	r := require.New(t)
+	w := New(RedirectApp())
+	w.FollowRedirects = true
+
+	r.Equal("GET ", w.HTML("/move/302").Post(User{Name: "mark"}).Body.String())
+	r.Equal("POST name=mark", w.HTML("/move/307").Post(User{Name: "mark"}).Body.String())
+	r.Equal("POST name=mark", w.HTML("/move/308").Post(User{Name: "mark"}).Body.String())
+}
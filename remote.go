@@ -0,0 +1,173 @@
+package httptest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Remote is an http.Handler that forwards requests over the network to
+// URL, so that the same HTML, JSON and XML calls can run against a live
+// server. Cookies, CSRF tokens, authentication, signing and redirects are
+// handled by the Handler as they are in-process. A request that can't be
+// sent sets the Response's Err; used as a plain http.Handler, the Remote
+// answers it with a 502 holding the error.
+type Remote struct {
+	URL *url.URL
+	// Client sends the requests. Its redirect policy is ignored so that
+	// the Handler's FollowRedirects applies.
+	Client *http.Client
+
+	mu      sync.Mutex
+	clients map[remoteClient]*http.Client
+}
+
+// NewRemote returns a Handler that sends its requests to baseURL, e.g.
+// "https://staging.example.com". Paths are appended to the base path. The
+// Handler's Host is the base host, so absolute redirects back to it are
+// followed through the Remote too. baseURL must be absolute.
+func NewRemote(baseURL string) (*Handler, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("remote URL %q is not absolute", baseURL)
+	}
+	return newRemote(u, &http.Client{}), nil
+}
+
+// NewRemoteServer returns a Handler that sends its requests to s using
+// s.Client(), which trusts the server's TLS certificate.
+func NewRemoteServer(s *Server) *Handler {
+	u, _ := url.Parse(s.URL)
+	return newRemote(u, s.Client())
+}
+
+func newRemote(u *url.URL, c *http.Client) *Handler {
+	w := New(&Remote{URL: u, Client: c})
+	w.Host = u.Host
+	return w
+}
+
+type clientCertKey struct{}
+
+func (rm *Remote) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if err := rm.serve(res, req); err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+	}
+}
+
+// serve sends req to the server and copies the reply into res. Nothing
+// is written to res when it returns an error.
+func (rm *Remote) serve(res http.ResponseWriter, req *http.Request) error {
+	out := req.Clone(req.Context())
+	out.URL = rm.target(req.URL)
+	out.Host = ""
+	out.RequestURI = ""
+	out.TLS = nil
+
+	cert, _ := req.Context().Value(clientCertKey{}).(*tls.Certificate)
+	c, err := rm.client(cert)
+	if err != nil {
+		return err
+	}
+	hres, err := c.Do(out)
+	if err != nil {
+		return err
+	}
+	defer hres.Body.Close()
+
+	for k, v := range hres.Header {
+		res.Header()[k] = v
+	}
+	res.WriteHeader(hres.StatusCode)
+	io.Copy(res, hres.Body)
+	for k, v := range hres.Trailer {
+		res.Header()[http.TrailerPrefix+k] = v
+	}
+	return nil
+}
+
+// target returns where a request for u is sent. A URL that already
+// points under URL is returned as is.
+func (rm *Remote) target(u *url.URL) *url.URL {
+	base := strings.TrimRight(rm.URL.Path, "/")
+	if u.Scheme == rm.URL.Scheme && u.Host == rm.URL.Host && (base == u.Path || strings.HasPrefix(u.Path, base+"/")) {
+		t := *u
+		return &t
+	}
+	t := *rm.URL
+	t.Path = base + u.Path
+	t.RawPath = ""
+	t.RawQuery = u.RawQuery
+	return &t
+}
+
+// retarget points req at the URL and Host that a Remote sends it to, so
+// that it is signed as the server will receive it.
+func (w *Handler) retarget(req *http.Request) {
+	if rm, ok := w.Handler.(*Remote); ok {
+		req.URL = rm.target(req.URL)
+		req.Host = req.URL.Host
+	}
+}
+
+type remoteClient struct {
+	base *http.Client
+	cert *tls.Certificate
+}
+
+// client returns a copy of Client that doesn't follow redirects and,
+// when cert is set, presents it to the server.
+func (rm *Remote) client(cert *tls.Certificate) (*http.Client, error) {
+	base := rm.Client
+	if base == nil {
+		base = http.DefaultClient
+	}
+	key := remoteClient{base, cert}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if c, ok := rm.clients[key]; ok {
+		return c, nil
+	}
+
+	c := *base
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	if cert != nil {
+		rt := c.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		t, ok := rt.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("can't present a client certificate with a %T", rt)
+		}
+		t = t.Clone()
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+		c.Transport = t
+	}
+
+	if rm.clients == nil {
+		rm.clients = map[remoteClient]*http.Client{}
+	}
+	rm.clients[key] = &c
+	return &c, nil
+}
+
+// withClientCert passes the Handler's ClientCert to a Remote, which
+// presents it in the TLS handshake.
+func withClientCert(req *http.Request, cert *tls.Certificate) {
+	*req = *req.WithContext(context.WithValue(req.Context(), clientCertKey{}, cert))
+}
@@ -0,0 +1,177 @@
+package httptest
+
+import (
+	"crypto/x509/pkix"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Remote_Sessions(t *testing.T) {
+	r := require.New(t)
+	s := NewServer(App())
+	defer s.Close()
+	w := NewRemoteServer(s)
+
+	res := w.HTML("/sessions/get").Get()
+	r.Equal(200, res.Code)
+	r.NotContains(res.Body.String(), "mark")
+	w.HTML("/sessions/set").Post(User{Name: "mark"})
+	res = w.HTML("/sessions/get").Get()
+	r.Equal("NAME:mark", res.Body.String())
+}
+
+func Test_Remote_Auth_And_Signing(t *testing.T) {
+	r := require.New(t)
+	local := New(SignedApp())
+	local.HmaxSecret = "secret"
+
+	s := NewServer(http.StripPrefix("/api", SignedApp()))
+	defer s.Close()
+	w, err := NewRemote(s.URL + "/api/")
+	r.NoError(err)
+	w.HmaxSecret = "secret"
+
+	want := local.JSON("/hook").Post(User{Name: "Mark"})
+	res := w.JSON("/hook").Post(User{Name: "Mark"})
+	r.Equal(200, res.Code)
+	r.Equal(want.Body.String(), res.Body.String())
+	r.Contains(res.Curl(), "'"+s.URL+"/api/hook'")
+
+	p := &mux{}
+	p.Handle("GET", "/me", func(res http.ResponseWriter, req *http.Request) {
+		u, pw, _ := req.BasicAuth()
+		res.Write([]byte(u + ":" + pw))
+	})
+	s2 := NewServer(p)
+	defer s2.Close()
+	w, err = NewRemote(s2.URL)
+	r.NoError(err)
+	req := w.HTML("/me")
+	req.SetBasicAuth("mark", "s3cret")
+	r.Equal("mark:s3cret", req.Get().Body.String())
+}
+
+func Test_Remote_SigV4_And_Canonical(t *testing.T) {
+	r := require.New(t)
+	server := &Canonical{HmaxConfig: HmaxConfig{Secret: "secret"}, Headers: []string{"Host"}}
+	verify := func(req *http.Request) error {
+		if strings.HasPrefix(req.Header.Get("Authorization"), sigV4Algorithm) {
+			return sigV4Example.VerifyRequest(req)
+		}
+		return server.VerifyRequest(req)
+	}
+	s := NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if err := verify(req); err != nil {
+			http.Error(res, err.Error(), 401)
+			return
+		}
+		res.Write([]byte(req.URL.Path))
+	}))
+	defer s.Close()
+
+	w, err := NewRemote(s.URL + "/api")
+	r.NoError(err)
+	sv := sigV4Example
+	w.SigV4 = &sv
+	res := w.JSON("/widgets?page=2").Post(User{Name: "Mark"})
+	r.Equal(200, res.Code, res.Body.String())
+	r.Equal("/api/widgets", res.Body.String())
+
+	w, err = NewRemote(s.URL + "/api")
+	r.NoError(err)
+	w.Canonical = &Canonical{HmaxConfig: HmaxConfig{Secret: "secret"}, Headers: []string{"Host"}}
+	res = w.JSON("/widgets").Post(User{Name: "Mark"})
+	r.Equal(200, res.Code, res.Body.String())
+
+	resp, err := w.Client().Get("http://example.com/widgets")
+	r.NoError(err)
+	r.Equal(200, resp.StatusCode)
+}
+
+func Test_Remote_FollowRedirects(t *testing.T) {
+	r := require.New(t)
+	p, err := NewOIDCProvider("my-app", "s3cret", OIDCUser{Subject: "mark", Email: "mark@example.com"})
+	r.NoError(err)
+	defer p.Close()
+
+	s := NewServer(LoginApp(p.Issuer()))
+	defer s.Close()
+	w := NewRemoteServer(s)
+	w.FollowRedirects = true
+	w.AllowRedirects(p.Server)
+	// LoginApp redirects back to app.test, which the Remote sends to s.
+	w.Host = "app.test"
+
+	res := w.HTML("/login").Get()
+	r.Equal(200, res.Code)
+	r.Equal("Welcome mark@example.com", res.Body.String())
+	r.Len(p.Authorizations(), 1)
+}
+
+func Test_Remote_ClientCert(t *testing.T) {
+	r := require.New(t)
+	ca, err := NewCA()
+	r.NoError(err)
+	s, err := ca.NewTLSServer(AdminApp())
+	r.NoError(err)
+	defer s.Close()
+
+	w := NewRemoteServer(s)
+	w.Handler.(*Remote).Client = ca.Client()
+	r.Error(w.HTML("/admin").Get().Err)
+
+	cert, err := ca.ClientCert(CertOptions{
+		Subject:        pkix.Name{CommonName: "admin"},
+		EmailAddresses: []string{"admin@example.com"},
+	})
+	r.NoError(err)
+	w.ClientCert = &cert
+	res := w.HTML("/admin").Get()
+	r.Equal(200, res.Code)
+	r.Equal("HELLO:admin:admin@example.com", res.Body.String())
+}
+
+func Test_Remote_Unreachable(t *testing.T) {
+	r := require.New(t)
+	s := NewServer(App())
+	s.Close()
+
+	w, err := NewRemote(s.URL)
+	r.NoError(err)
+	res := w.HTML("/get").Get()
+	r.Error(res.Err)
+	r.Contains(res.Err.Error(), "connection refused")
+	r.Equal(0, res.Code)
+
+	_, err = w.JSON("/get").Do("GET", nil)
+	r.Error(err)
+}
+
+func Test_Remote_Target(t *testing.T) {
+	r := require.New(t)
+	base, err := url.Parse("https://staging.example.com/api")
+	r.NoError(err)
+	rm := &Remote{URL: base}
+	for in, want := range map[string]string{
+		"/widgets?page=2":                           "https://staging.example.com/api/widgets?page=2",
+		"https://staging.example.com/api":           "https://staging.example.com/api",
+		"https://staging.example.com/api/widgets":   "https://staging.example.com/api/widgets",
+		"https://staging.example.com/apiv2/widgets": "https://staging.example.com/api/apiv2/widgets",
+	} {
+		u, err := url.Parse(in)
+		r.NoError(err)
+		r.Equal(want, rm.target(u).String(), in)
+	}
+}
+
+func Test_NewRemote_Invalid_URL(t *testing.T) {
+	r := require.New(t)
+	_, err := NewRemote("http://[::1")
+	r.Error(err)
+	_, err = NewRemote("staging.example.com")
+	r.Error(err)
+}
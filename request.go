@@ -4,13 +4,11 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
-	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"strings"
 
 	"github.com/gobuffalo/httptest/internal/takeon/github.com/ajg/form"
-	"github.com/gobuffalo/httptest/internal/takeon/github.com/markbates/hmax"
 )
 
 type Request struct {
@@ -19,6 +17,13 @@ type Request struct {
 	Headers  map[string]string
 	Username string
 	Password string
+	// Digest answers HTTP Digest challenges with Username and Password
+	// instead of sending them as Basic auth.
+	Digest bool
+	// Authenticator, if set, is used instead of Username and Password
+	// and the Handler's Authenticator.
+	Authenticator Authenticator
+	hops          int
 }
 
 func (r *Request) SetBasicAuth(username, password string) {
@@ -38,14 +43,12 @@ func (r *Request) Delete() *Response {
 
 func (r *Request) Post(body interface{}) *Response {
 	req, _ := http.NewRequest("POST", r.URL, toReader(body))
-	r.Headers["Content-Type"] = "application/x-www-form-urlencoded"
-	return r.Perform(req)
+	return r.perform(req, "application/x-www-form-urlencoded")
 }
 
 func (r *Request) Put(body interface{}) *Response {
 	req, _ := http.NewRequest("PUT", r.URL, toReader(body))
-	r.Headers["Content-Type"] = "application/x-www-form-urlencoded"
-	return r.Perform(req)
+	return r.perform(req, "application/x-www-form-urlencoded")
 }
 
 func (r *Request) Do(method string, body interface{}) (*Response, error) {
@@ -53,27 +56,29 @@ func (r *Request) Do(method string, body interface{}) (*Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	return r.Perform(req), nil
+	res := r.Perform(req)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	return res, nil
 }
 
 func (r *Request) Perform(req *http.Request) *Response {
-	if r.handler.HmaxSecret != "" {
-		hmax.SignRequest(req, []byte(r.handler.HmaxSecret))
-	}
-	if r.Username != "" || r.Password != "" {
-		req.SetBasicAuth(r.Username, r.Password)
-	}
-	res := &Response{httptest.NewRecorder()}
-	for key, value := range r.Headers {
-		req.Header.Set(key, value)
+	return r.perform(req, r.Headers["Content-Type"])
+}
+
+// perform sends req with the given Content-Type, if any, overriding the
+// Request's Headers.
+func (r *Request) perform(req *http.Request, contentType string) *Response {
+	res := r.handler.perform(req, r.URL, r.Headers, contentType, r.credentials())
+	if res.Err != nil {
+		return res
 	}
-	req.RequestURI = r.URL
-	req.Header.Set("Cookie", r.handler.Cookies)
-	r.handler.ServeHTTP(res, req)
+	return r.follow(res)
+}
 
-	c := res.Header().Get("Set-Cookie")
-	r.handler.Cookies = c
-	return res
+func (r *Request) credentials() credentials {
+	return credentials{r.Authenticator, r.Username, r.Password, r.Digest}
 }
 
 func toReader(body interface{}) io.Reader {
@@ -84,6 +89,12 @@ func toReader(body interface{}) io.Reader {
 }
 
 func toURLValues(body interface{}) url.Values {
+	if v, ok := body.(url.Values); ok {
+		return v
+	}
+	if body == nil {
+		return url.Values{}
+	}
 	m := map[string]interface{}{}
 	rv := reflect.Indirect(reflect.ValueOf(body))
 	rt := rv.Type()
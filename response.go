@@ -1,9 +1,21 @@
 package httptest
 
-import "net/http/httptest"
+import (
+	"net/http"
+	"net/http/httptest"
+)
 
 type Response struct {
 	*httptest.ResponseRecorder
+	handler *Handler
+	url     string
+	// req and reqBody are the request that produced the response.
+	req     *http.Request
+	reqBody []byte
+	// Err is set when the request could not be sent, e.g. because its
+	// Authenticator or signing failed; Code is then 0 and the body holds
+	// the error. It is also set on a redirect that could not be followed.
+	Err error
 }
 
 func (r *Response) Location() string {
@@ -13,3 +25,11 @@ func (r *Response) Location() string {
 func (r *Response) CloseNotify() <-chan bool {
 	return make(chan bool)
 }
+
+// fail records err as the reason res was never sent.
+func (r *Response) fail(err error) *Response {
+	r.Err = err
+	r.Code = 0
+	r.Body.WriteString(err.Error())
+	return r
+}
@@ -0,0 +1,68 @@
+package httptest
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var sigV4Example = SigV4{
+	AccessKey: "AKIDEXAMPLE",
+	SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	Region:    "us-east-1",
+	Service:   "iam",
+	Time:      time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC),
+}
+
+// Test_SigV4_Example checks the signer against the example in the AWS
+// Signature Version 4 documentation.
+func Test_SigV4_Example(t *testing.T) {
+	r := require.New(t)
+
+	req, err := http.NewRequest("GET", "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	r.NoError(err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	r.NoError(sigV4Example.SignRequest(req))
+	r.Equal("20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	r.Equal("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7", req.Header.Get("Authorization"))
+	r.NoError(sigV4Example.VerifyRequest(req))
+
+	req.URL.RawQuery = "Action=DeleteUser&Version=2010-05-08"
+	r.Error(sigV4Example.VerifyRequest(req))
+}
+
+func Test_SigV4_Handler(t *testing.T) {
+	r := require.New(t)
+
+	p := &mux{}
+	p.Handle("POST", "/widgets", func(res http.ResponseWriter, req *http.Request) {
+		if err := sigV4Example.VerifyRequest(req); err != nil {
+			res.WriteHeader(403)
+			fmt.Fprint(res, err)
+			return
+		}
+		fmt.Fprint(res, req.Header.Get("Authorization"))
+	})
+
+	w := New(p)
+	res := w.JSON("/widgets").Post(User{Name: "Mark"})
+	r.Equal(403, res.Code)
+
+	s := sigV4Example
+	w.SigV4 = &s
+	res = w.JSON("/widgets").Post(User{Name: "Mark"})
+	r.Equal(200, res.Code)
+	first := res.Body.String()
+	r.Contains(first, "Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request")
+
+	res = w.JSON("/widgets").Post(User{Name: "Mark"})
+	r.Equal(first, res.Body.String())
+
+	s.SecretKey = "wrong"
+	res = w.JSON("/widgets").Post(User{Name: "Mark"})
+	r.Equal(403, res.Code)
+}
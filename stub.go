@@ -0,0 +1,270 @@
+package httptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Stub is a test server standing in for an HTTP service the code under
+// test calls. Expectations are declared with On, and Verify reports any
+// expected call that didn't happen the expected number of times and any
+// request that matched no expectation.
+type Stub struct {
+	*Server
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	unexpected   []string
+}
+
+// NewStub starts a new Stub. Close it when done.
+func NewStub() *Stub {
+	s := &Stub{}
+	s.Server = NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// StubResponse is a canned response. Delay is waited before replying.
+type StubResponse struct {
+	Status  int
+	Headers map[string]string
+	Body    string
+	Delay   time.Duration
+}
+
+// Expectation matches requests to a Stub and replies to them. Responses
+// are used in order, with the last one repeated.
+type Expectation struct {
+	method    string
+	pattern   string
+	query     map[string]string
+	headers   map[string]string
+	json      interface{}
+	hasJSON   bool
+	times     int
+	responses []StubResponse
+	calls     int
+}
+
+// On declares an expected request. The path pattern may contain {name}
+// segments, which match any single segment, and may end in /* to match
+// any suffix.
+func (s *Stub) On(method, pattern string) *Expectation {
+	e := &Expectation{
+		method:  strings.ToUpper(method),
+		pattern: pattern,
+		query:   map[string]string{},
+		headers: map[string]string{},
+		times:   -1,
+	}
+	s.mu.Lock()
+	s.expectations = append(s.expectations, e)
+	s.mu.Unlock()
+	return e
+}
+
+// WithQuery requires the query parameter to have the given value.
+func (e *Expectation) WithQuery(key, value string) *Expectation {
+	e.query[key] = value
+	return e
+}
+
+// WithHeader requires the header to have the given value.
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	e.headers[key] = value
+	return e
+}
+
+// WithJSON requires the body to be JSON equal to v.
+func (e *Expectation) WithJSON(v interface{}) *Expectation {
+	e.json = v
+	e.hasJSON = true
+	return e
+}
+
+// Times sets how many times the request must be made. By default it
+// must be made at least once.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Reply adds a response with the given status and body.
+func (e *Expectation) Reply(status int, body string) *Expectation {
+	return e.ReplyWith(StubResponse{Status: status, Body: body})
+}
+
+// ReplyJSON adds a response with the given status and v encoded as JSON.
+func (e *Expectation) ReplyJSON(status int, v interface{}) *Expectation {
+	b, _ := json.Marshal(v)
+	return e.ReplyWith(StubResponse{
+		Status:  status,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    string(b),
+	})
+}
+
+// ReplyWith adds a response.
+func (e *Expectation) ReplyWith(r StubResponse) *Expectation {
+	e.responses = append(e.responses, r)
+	return e
+}
+
+func (e *Expectation) String() string {
+	s := e.method + " " + e.pattern
+	for k, v := range e.query {
+		s += fmt.Sprintf(" query[%s]=%q", k, v)
+	}
+	for k, v := range e.headers {
+		s += fmt.Sprintf(" header[%s]=%q", k, v)
+	}
+	if e.hasJSON {
+		b, _ := json.Marshal(e.json)
+		s += " json=" + string(b)
+	}
+	return s
+}
+
+// Verify reports, through t, every expectation that wasn't met and
+// every request that matched no expectation.
+func (s *Stub) Verify(t testing.TB) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.expectations {
+		switch {
+		case e.times < 0 && e.calls == 0:
+			t.Errorf("expected %s to be called, but it wasn't", e)
+		case e.times >= 0 && e.calls != e.times:
+			t.Errorf("expected %s to be called %d time(s), but it was called %d time(s)", e, e.times, e.calls)
+		}
+	}
+	for _, u := range s.unexpected {
+		t.Errorf("unexpected request %s", u)
+	}
+}
+
+func (s *Stub) serve(res http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	s.mu.Lock()
+	// An expectation that has been called Times times is skipped in
+	// favour of later ones. If all that match are used up, the first
+	// answers, and Verify reports it as called too often.
+	var match, spent *Expectation
+	var reasons []string
+	for _, e := range s.expectations {
+		why := e.mismatch(req, body)
+		if why == "" && e.times >= 0 && e.calls >= e.times {
+			if spent == nil {
+				spent = e
+			}
+			continue
+		}
+		if why == "" {
+			match = e
+			break
+		}
+		if e.method == req.Method && matchPath(e.pattern, req.URL.Path) {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", e, why))
+		}
+	}
+	if match == nil {
+		match = spent
+	}
+	if match == nil {
+		desc := req.Method + " " + req.URL.RequestURI()
+		if len(body) > 0 {
+			desc += " " + string(body)
+		}
+		if len(reasons) > 0 {
+			desc += "\n\tclosest expectations:\n\t\t" + strings.Join(reasons, "\n\t\t")
+		}
+		s.unexpected = append(s.unexpected, desc)
+		s.mu.Unlock()
+		http.Error(res, "httptest.Stub: unexpected request "+desc, http.StatusNotImplemented)
+		return
+	}
+	r := StubResponse{Status: http.StatusOK}
+	if len(match.responses) > 0 {
+		i := match.calls
+		if i >= len(match.responses) {
+			i = len(match.responses) - 1
+		}
+		r = match.responses[i]
+	}
+	match.calls++
+	s.mu.Unlock()
+
+	if r.Delay > 0 {
+		time.Sleep(r.Delay)
+	}
+	for k, v := range r.Headers {
+		res.Header().Set(k, v)
+	}
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+	res.WriteHeader(r.Status)
+	fmt.Fprint(res, r.Body)
+}
+
+// mismatch returns why req doesn't match the expectation, or "" if it
+// does.
+func (e *Expectation) mismatch(req *http.Request, body []byte) string {
+	if e.method != req.Method {
+		return "method " + req.Method
+	}
+	if !matchPath(e.pattern, req.URL.Path) {
+		return "path " + req.URL.Path
+	}
+	q := req.URL.Query()
+	for k, v := range e.query {
+		if q.Get(k) != v {
+			return fmt.Sprintf("query[%s] was %q", k, q.Get(k))
+		}
+	}
+	for k, v := range e.headers {
+		if req.Header.Get(k) != v {
+			return fmt.Sprintf("header[%s] was %q", k, req.Header.Get(k))
+		}
+	}
+	if e.hasJSON {
+		var want, got interface{}
+		b, _ := json.Marshal(e.json)
+		json.Unmarshal(b, &want)
+		if err := json.Unmarshal(body, &got); err != nil || !reflect.DeepEqual(want, got) {
+			return fmt.Sprintf("json was %s", body)
+		}
+	}
+	return ""
+}
+
+func matchPath(pattern, path string) bool {
+	ps := strings.Split(strings.Trim(pattern, "/"), "/")
+	xs := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range ps {
+		if p == "*" && i == len(ps)-1 {
+			return true
+		}
+		if i >= len(xs) {
+			return false
+		}
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			continue
+		}
+		if p != xs[i] {
+			return false
+		}
+	}
+	return len(ps) == len(xs)
+}
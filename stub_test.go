@@ -0,0 +1,144 @@
+package httptest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingT captures the errors reported through testing.TB.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func stubGet(t *testing.T, u string) (int, string) {
+	res, err := http.Get(u)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, string(b)
+}
+
+func Test_Stub_Matching(t *testing.T) {
+	r := require.New(t)
+	s := NewStub()
+	defer s.Close()
+
+	s.On("GET", "/users/{id}").WithQuery("expand", "true").ReplyJSON(200, map[string]string{"name": "Mark"})
+	s.On("GET", "/files/*").WithHeader("X-Token", "abc").Reply(200, "file")
+	s.On("POST", "/users").WithJSON(map[string]interface{}{"name": "Mark", "age": 42}).Reply(201, "created").Times(1)
+
+	code, body := stubGet(t, s.URL+"/users/1?expand=true")
+	r.Equal(200, code)
+	r.JSONEq(`{"name":"Mark"}`, body)
+
+	req, _ := http.NewRequest("GET", s.URL+"/files/a/b.txt", nil)
+	req.Header.Set("X-Token", "abc")
+	res, err := http.DefaultClient.Do(req)
+	r.NoError(err)
+	res.Body.Close()
+	r.Equal(200, res.StatusCode)
+
+	res, err = http.Post(s.URL+"/users", "application/json", bytes.NewBufferString(`{"age":42,"name":"Mark"}`))
+	r.NoError(err)
+	res.Body.Close()
+	r.Equal(201, res.StatusCode)
+
+	s.Verify(t)
+}
+
+func Test_Stub_Sequence_And_Delay(t *testing.T) {
+	r := require.New(t)
+	s := NewStub()
+	defer s.Close()
+
+	s.On("GET", "/flaky").
+		Reply(503, "down").
+		ReplyWith(StubResponse{Status: 200, Body: "up", Delay: 20 * time.Millisecond, Headers: map[string]string{"X-Try": "2"}}).
+		Times(3)
+
+	code, body := stubGet(t, s.URL+"/flaky")
+	r.Equal(503, code)
+	r.Equal("down", body)
+
+	start := time.Now()
+	code, body = stubGet(t, s.URL+"/flaky")
+	r.Equal(200, code)
+	r.Equal("up", body)
+	r.True(time.Since(start) >= 20*time.Millisecond)
+
+	code, _ = stubGet(t, s.URL+"/flaky")
+	r.Equal(200, code)
+
+	s.Verify(t)
+}
+
+func Test_Stub_Times_Falls_Through(t *testing.T) {
+	r := require.New(t)
+	s := NewStub()
+	defer s.Close()
+
+	s.On("GET", "/token").Reply(200, "first").Times(1)
+	s.On("GET", "/token").Reply(200, "second").Times(1)
+
+	_, body := stubGet(t, s.URL+"/token")
+	r.Equal("first", body)
+	_, body = stubGet(t, s.URL+"/token")
+	r.Equal("second", body)
+	s.Verify(t)
+
+	_, body = stubGet(t, s.URL+"/token")
+	r.Equal("first", body)
+	rt := &recordingT{}
+	s.Verify(rt)
+	r.Len(rt.errors, 1)
+	r.Contains(rt.errors[0], "expected GET /token to be called 1 time(s), but it was called 2 time(s)")
+}
+
+func Test_Stub_Verify_Reports(t *testing.T) {
+	r := require.New(t)
+	s := NewStub()
+	defer s.Close()
+
+	s.On("GET", "/never").Reply(200, "")
+	s.On("GET", "/once").Times(1)
+	s.On("GET", "/users/{id}").WithQuery("expand", "true")
+
+	stubGet(t, s.URL+"/once")
+	stubGet(t, s.URL+"/once")
+	code, body := stubGet(t, s.URL+"/users/1?expand=false")
+	r.Equal(501, code)
+	r.Contains(body, "unexpected request GET /users/1?expand=false")
+
+	rt := &recordingT{}
+	s.Verify(rt)
+	r.Len(rt.errors, 4)
+	r.Contains(rt.errors[0], "expected GET /never to be called, but it wasn't")
+	r.Contains(rt.errors[1], "expected GET /once to be called 1 time(s), but it was called 2 time(s)")
+	r.Contains(rt.errors[3], "unexpected request GET /users/1?expand=false")
+	r.Contains(rt.errors[3], `query[expand] was "false"`)
+}
+
+func Test_Stub_Match_Path(t *testing.T) {
+	r := require.New(t)
+
+	r.True(matchPath("/users/{id}", "/users/1"))
+	r.False(matchPath("/users/{id}", "/users/1/edit"))
+	r.False(matchPath("/users/{id}", "/users"))
+	r.True(matchPath("/files/*", "/files/a/b"))
+	r.True(matchPath("/", "/"))
+	r.False(matchPath("/a", "/b"))
+}
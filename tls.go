@@ -0,0 +1,185 @@
+package httptest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+)
+
+// CA is an in-memory certificate authority for issuing test server and
+// client certificates.
+type CA struct {
+	Certificate *x509.Certificate
+	Key         *ecdsa.PrivateKey
+}
+
+// CertOptions describe a certificate issued by a CA. NotBefore and
+// NotAfter default to an hour ago and a day from now.
+type CertOptions struct {
+	Subject        pkix.Name
+	DNSNames       []string
+	IPAddresses    []net.IP
+	EmailAddresses []string
+	NotBefore      time.Time
+	NotAfter       time.Time
+}
+
+// NewCA creates a new self-signed certificate authority.
+func NewCA() (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "httptest CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{Certificate: cert, Key: key}, nil
+}
+
+// PEM returns the CA certificate PEM encoded.
+func (ca *CA) PEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate.Raw})
+}
+
+// Pool returns a certificate pool containing only the CA.
+func (ca *CA) Pool() *x509.CertPool {
+	p := x509.NewCertPool()
+	p.AddCert(ca.Certificate)
+	return p
+}
+
+// ServerCert issues a server certificate for the given host names and
+// IP addresses.
+func (ca *CA) ServerCert(hosts ...string) (tls.Certificate, error) {
+	opts := CertOptions{Subject: pkix.Name{CommonName: "httptest server"}}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			opts.IPAddresses = append(opts.IPAddresses, ip)
+			continue
+		}
+		opts.DNSNames = append(opts.DNSNames, h)
+	}
+	return ca.issue(opts, x509.ExtKeyUsageServerAuth)
+}
+
+// ClientCert issues a client certificate.
+func (ca *CA) ClientCert(opts CertOptions) (tls.Certificate, error) {
+	return ca.issue(opts, x509.ExtKeyUsageClientAuth)
+}
+
+// NewTLSServer starts a server for h that presents a certificate issued
+// by the CA for 127.0.0.1 and localhost, and requires clients to present
+// a certificate issued by the CA.
+func (ca *CA) NewTLSServer(h http.Handler) (*Server, error) {
+	cert, err := ca.ServerCert("127.0.0.1", "::1", "localhost")
+	if err != nil {
+		return nil, err
+	}
+	s := NewUnstartedServer(h)
+	s.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    ca.Pool(),
+	}
+	s.StartTLS()
+	return s, nil
+}
+
+// Client returns an *http.Client that trusts the CA and presents the
+// given client certificates.
+func (ca *CA) Client(certs ...tls.Certificate) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:      ca.Pool(),
+				Certificates: certs,
+			},
+		},
+	}
+}
+
+func (ca *CA) issue(opts CertOptions, usage x509.ExtKeyUsage) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if opts.NotBefore.IsZero() {
+		opts.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if opts.NotAfter.IsZero() {
+		opts.NotAfter = time.Now().Add(24 * time.Hour)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:   serial,
+		Subject:        opts.Subject,
+		DNSNames:       opts.DNSNames,
+		IPAddresses:    opts.IPAddresses,
+		EmailAddresses: opts.EmailAddresses,
+		NotBefore:      opts.NotBefore,
+		NotAfter:       opts.NotAfter,
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Certificate, &key.PublicKey, ca.Key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der, ca.Certificate.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+// applyTLS makes in-process requests look as if they arrived over TLS
+// with the Handler's ClientCert presented. A Remote presents it in the
+// TLS handshake instead.
+func (w *Handler) applyTLS(req *http.Request) {
+	if w.ClientCert == nil {
+		return
+	}
+	if _, ok := w.Handler.(*Remote); ok {
+		withClientCert(req, w.ClientCert)
+		return
+	}
+	state := &tls.ConnectionState{
+		Version:           tls.VersionTLS13,
+		HandshakeComplete: true,
+	}
+	for _, der := range w.ClientCert.Certificate {
+		if c, err := x509.ParseCertificate(der); err == nil {
+			state.PeerCertificates = append(state.PeerCertificates, c)
+		}
+	}
+	req.TLS = state
+}
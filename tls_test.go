@@ -0,0 +1,110 @@
+package httptest
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func AdminApp() http.Handler {
+	p := &mux{}
+	p.Handle("GET", "/admin", func(res http.ResponseWriter, req *http.Request) {
+		if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+			res.WriteHeader(401)
+			return
+		}
+		c := req.TLS.PeerCertificates[0]
+		if c.Subject.CommonName != "admin" {
+			res.WriteHeader(403)
+			return
+		}
+		fmt.Fprint(res, "HELLO:"+c.Subject.CommonName+":"+c.EmailAddresses[0])
+	})
+	return p
+}
+
+func Test_ClientCert_In_Process(t *testing.T) {
+	r := require.New(t)
+	ca, err := NewCA()
+	r.NoError(err)
+
+	w := New(AdminApp())
+	res := w.HTML("/admin").Get()
+	r.Equal(401, res.Code)
+
+	cert, err := ca.ClientCert(CertOptions{
+		Subject:        pkix.Name{CommonName: "admin"},
+		EmailAddresses: []string{"admin@example.com"},
+	})
+	r.NoError(err)
+	w.ClientCert = &cert
+
+	res = w.HTML("/admin").Get()
+	r.Equal(200, res.Code)
+	r.Equal("HELLO:admin:admin@example.com", res.Body.String())
+
+	cert, err = ca.ClientCert(CertOptions{Subject: pkix.Name{CommonName: "guest"}})
+	r.NoError(err)
+	w.ClientCert = &cert
+	r.Equal(403, w.JSON("/admin").Get().Code)
+}
+
+func Test_ClientCert_TLS_Server(t *testing.T) {
+	r := require.New(t)
+	ca, err := NewCA()
+	r.NoError(err)
+
+	s, err := ca.NewTLSServer(AdminApp())
+	r.NoError(err)
+	defer s.Close()
+
+	cert, err := ca.ClientCert(CertOptions{
+		Subject:        pkix.Name{CommonName: "admin"},
+		EmailAddresses: []string{"admin@example.com"},
+	})
+	r.NoError(err)
+
+	res, err := ca.Client(cert).Get(s.URL + "/admin")
+	r.NoError(err)
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	r.NoError(err)
+	r.Equal(200, res.StatusCode)
+	r.Equal("HELLO:admin:admin@example.com", string(b))
+
+	_, err = ca.Client().Get(s.URL + "/admin")
+	r.Error(err)
+
+	expired, err := ca.ClientCert(CertOptions{
+		Subject:   pkix.Name{CommonName: "admin"},
+		NotBefore: time.Now().Add(-48 * time.Hour),
+		NotAfter:  time.Now().Add(-24 * time.Hour),
+	})
+	r.NoError(err)
+	_, err = ca.Client(expired).Get(s.URL + "/admin")
+	r.Error(err)
+}
+
+func Test_CA_ServerCert(t *testing.T) {
+	r := require.New(t)
+	ca, err := NewCA()
+	r.NoError(err)
+
+	cert, err := ca.ServerCert("example.test", "10.0.0.1")
+	r.NoError(err)
+	r.Equal([]string{"example.test"}, cert.Leaf.DNSNames)
+	r.Equal("10.0.0.1", cert.Leaf.IPAddresses[0].String())
+
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{
+		DNSName: "example.test",
+		Roots:   ca.Pool(),
+	})
+	r.NoError(err)
+	r.Contains(string(ca.PEM()), "BEGIN CERTIFICATE")
+}
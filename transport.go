@@ -0,0 +1,52 @@
+package httptest
+
+import (
+	"net/http"
+	"net/http/httptest"
+)
+
+// RoundTrip sends req straight to the handler's ServeHTTP, making the
+// Handler an http.RoundTripper. The Handler's headers, cookies, CSRF
+// token, authentication and signing are applied as they are for HTML,
+// JSON and XML requests, but headers and cookies that req already has
+// are kept. req.Body is closed.
+func (w *Handler) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	sreq, err := w.serverRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	res := &Response{ResponseRecorder: httptest.NewRecorder(), handler: w, url: req.URL.String()}
+	w.serve(res, sreq, credentials{})
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	w.mergeCookies(res)
+	w.learnCSRF(res)
+
+	hres := res.Result()
+	hres.Request = req
+	return hres, nil
+}
+
+// serverRequest returns a copy of req, as a server would receive it,
+// with the Handler's headers, cookies, CSRF token and authentication
+// applied.
+func (w *Handler) serverRequest(req *http.Request) (*http.Request, error) {
+	sreq := req.Clone(req.Context())
+	if sreq.Body == nil {
+		sreq.Body = http.NoBody
+	}
+	if err := w.prepare(sreq, w.Headers, sreq.Header.Get("Content-Type"), credentials{}); err != nil {
+		return nil, err
+	}
+	return sreq, nil
+}
+
+// Client returns an *http.Client that sends its requests to the handler
+// in-process. Use any base URL, e.g. "http://example.com".
+func (w *Handler) Client() *http.Client {
+	return &http.Client{Transport: w}
+}
@@ -0,0 +1,128 @@
+package httptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// widgetClient is an SDK style client for JSONApp.
+type widgetClient struct {
+	base   string
+	client *http.Client
+}
+
+func (c widgetClient) Create(name string) (jBody, error) {
+	jb := jBody{}
+	res, err := c.client.Post(c.base+"/post", "application/json", strings.NewReader(fmt.Sprintf(`{"name":%q}`, name)))
+	if err != nil {
+		return jb, err
+	}
+	defer res.Body.Close()
+	err = json.NewDecoder(res.Body).Decode(&jb)
+	return jb, err
+}
+
+func Test_Handler_Client(t *testing.T) {
+	r := require.New(t)
+	w := New(JSONApp())
+
+	c := widgetClient{base: "http://example.com", client: w.Client()}
+	jb, err := c.Create("Mark")
+	r.NoError(err)
+	r.Equal("POST", jb.Method)
+	r.Equal("Mark", jb.Name)
+}
+
+func Test_Handler_RoundTrip(t *testing.T) {
+	r := require.New(t)
+
+	p := &mux{}
+	p.Handle("GET", "/trailers", func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Trailer", "X-Checksum")
+		res.Header().Set("X-Seen-Host", req.Host)
+		res.Header().Set("X-Seen-Foo", req.Header.Get("Foo"))
+		res.Header().Set("X-Seen-Auth", req.Header.Get("Authorization"))
+		res.WriteHeader(202)
+		fmt.Fprint(res, "body")
+		res.Header().Set("X-Checksum", "abc")
+	})
+	p.Handle("GET", "/redirect", func(res http.ResponseWriter, req *http.Request) {
+		http.Redirect(res, req, "/trailers", http.StatusFound)
+	})
+	w := New(p)
+	w.Headers["Foo"] = "bar"
+	w.Authenticator = BearerAuth("tok")
+
+	req, err := http.NewRequest("GET", "http://api.test/redirect", nil)
+	r.NoError(err)
+	res, err := w.Client().Do(req)
+	r.NoError(err)
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	r.NoError(err)
+	r.Equal(202, res.StatusCode)
+	r.Equal("body", string(b))
+	r.Equal("abc", res.Trailer.Get("X-Checksum"))
+	r.Equal("api.test", res.Header.Get("X-Seen-Host"))
+	r.Equal("bar", res.Header.Get("X-Seen-Foo"))
+	r.Equal("Bearer tok", res.Header.Get("X-Seen-Auth"))
+	r.Equal("/trailers", res.Request.URL.Path)
+}
+
+func Test_Handler_RoundTrip_Cookies(t *testing.T) {
+	r := require.New(t)
+	w := New(App())
+	c := w.Client()
+
+	res, err := c.PostForm("http://example.com/sessions/set", map[string][]string{"name": {"mark"}})
+	r.NoError(err)
+	res.Body.Close()
+
+	res, err = c.Get("http://example.com/sessions/get")
+	r.NoError(err)
+	defer res.Body.Close()
+	b, _ := ioutil.ReadAll(res.Body)
+	r.Equal("NAME:mark", string(b))
+}
+
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func Test_Handler_RoundTrip_Keeps_Request_Headers(t *testing.T) {
+	r := require.New(t)
+	w := New(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(res, "%s|%s", req.Header.Get("Accept"), req.Header.Get("Cookie"))
+	}))
+	w.Headers["Accept"] = "text/html"
+	w.Headers["X-Api"] = "1"
+	w.Cookies = "session=handler; theme=dark"
+
+	body := &closeTracker{Reader: strings.NewReader("{}")}
+	req, err := http.NewRequest("POST", "http://api.test/", body)
+	r.NoError(err)
+	req.Header.Set("Accept", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "jar"})
+
+	res, err := w.RoundTrip(req)
+	r.NoError(err)
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	r.NoError(err)
+	r.Equal("application/json|session=jar; theme=dark", string(b))
+	r.True(body.closed)
+}
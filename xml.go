@@ -4,9 +4,6 @@ import (
 	"bytes"
 	"encoding/xml"
 	"net/http"
-	"net/http/httptest"
-
-	"github.com/gobuffalo/httptest/internal/takeon/github.com/markbates/hmax"
 )
 
 type XML struct {
@@ -15,6 +12,12 @@ type XML struct {
 	Headers  map[string]string
 	Username string
 	Password string
+	// Digest answers HTTP Digest challenges with Username and Password
+	// instead of sending them as Basic auth.
+	Digest bool
+	// Authenticator, if set, is used instead of Username and Password
+	// and the Handler's Authenticator.
+	Authenticator Authenticator
 }
 
 type XMLResponse struct {
@@ -54,18 +57,9 @@ func (r *XML) Patch(body interface{}) *XMLResponse {
 }
 
 func (r *XML) perform(req *http.Request) *XMLResponse {
-	if r.handler.HmaxSecret != "" {
-		hmax.SignRequest(req, []byte(r.handler.HmaxSecret))
-	}
-	if r.Username != "" || r.Password != "" {
-		req.SetBasicAuth(r.Username, r.Password)
-	}
-	res := &XMLResponse{&Response{httptest.NewRecorder()}}
-	for key, value := range r.Headers {
-		req.Header.Set(key, value)
-	}
-	req.Header.Set("Cookie", r.handler.Cookies)
-	r.handler.ServeHTTP(res, req)
-	r.handler.Cookies = res.Header().Get("Set-Cookie")
-	return res
+	return &XMLResponse{r.handler.perform(req, r.URL, r.Headers, "", r.credentials())}
+}
+
+func (r *XML) credentials() credentials {
+	return credentials{r.Authenticator, r.Username, r.Password, r.Digest}
 }